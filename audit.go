@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type AuditEntry struct {
+	Time         time.Time   `json:"time"`
+	Operator     string      `json:"operator"`
+	ZoneID       string      `json:"zone_id"`
+	ZoneName     string      `json:"zone_name"`
+	AccountID    string      `json:"account_id,omitempty"`
+	Key          string      `json:"key"`
+	Before       interface{} `json:"before"`
+	After        interface{} `json:"after"`
+	Status       int         `json:"status"`
+	Error        string      `json:"error,omitempty"`
+	ConfigCommit string      `json:"config_commit,omitempty"`
+}
+
+// AuditJournal appends an entry for every change that is applied, or fails
+// to apply, to a JSON Lines file. The fields other than File are copied to
+// every entry.
+//
+// Journals of zones have a ZoneID. Changes that are made to the zone's
+// account, such as to its Workers scripts, are recorded against the zone
+// with the account's ID in AccountID.
+type AuditJournal struct {
+	File         string
+	Operator     string
+	ZoneID       string
+	ZoneName     string
+	ConfigCommit string
+}
+
+func (a *AuditJournal) Record(entry AuditEntry) error {
+	if a.ZoneID != "" && entry.ZoneID != a.ZoneID {
+		entry.AccountID = entry.ZoneID
+		entry.ZoneID = a.ZoneID
+	}
+	entry.Operator = a.Operator
+	entry.ZoneName = a.ZoneName
+	entry.ConfigCommit = a.ConfigCommit
+
+	bs, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(a.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(bs, '\n'))
+
+	return err
+}
+
+type AuditFilter struct {
+	Zone  string
+	Key   string
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether an entry satisfies every non-zero field of the
+// filter. Zone matches either the zone ID or name.
+func (f AuditFilter) Match(entry AuditEntry) bool {
+	if f.Zone != "" && f.Zone != entry.ZoneID && f.Zone != entry.ZoneName {
+		return false
+	}
+	if f.Key != "" && f.Key != entry.Key {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+func LoadAuditEntries(file string, filter AuditFilter) ([]AuditEntry, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	entries := []AuditEntry{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, err
+		}
+
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// ConfigFileCommit returns the git commit that last changed a file, with a
+// "-dirty" suffix if the file has uncommitted changes.
+func ConfigFileCommit(file string) (string, error) {
+	dir, name := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+
+	git := exec.Command("git", "log", "-n", "1", "--format=%H", "--", name)
+	git.Dir = dir
+	out, err := git.Output()
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(string(out))
+
+	git = exec.Command("git", "status", "--porcelain", "--", name)
+	git.Dir = dir
+	out, err = git.Output()
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(out)) != "" {
+		commit += "-dirty"
+	}
+
+	return commit, nil
+}
@@ -0,0 +1,141 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ = Describe("AuditJournal", func() {
+	var (
+		tempDir  string
+		tempFile string
+		journal  *AuditJournal
+	)
+
+	day := func(d int) time.Time {
+		return time.Date(2014, time.October, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "cloudflare-configure")
+		Expect(err).To(BeNil())
+
+		tempFile = filepath.Join(tempDir, "audit.jsonl")
+		journal = &AuditJournal{
+			File:         tempFile,
+			Operator:     "user@example.com",
+			ZoneName:     "foo.example.com",
+			ConfigCommit: "abc123",
+		}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		Expect(err).To(BeNil())
+	})
+
+	Describe("Record()", func() {
+		It("should append one JSON line per entry", func() {
+			Expect(journal.Record(AuditEntry{
+				Time:   day(17),
+				ZoneID: "123",
+				Key:    "always_online",
+				Before: "off",
+				After:  "on",
+				Status: 200,
+			})).To(BeNil())
+			Expect(journal.Record(AuditEntry{
+				Time:   day(18),
+				ZoneID: "123",
+				Key:    "unicorns",
+				After:  "mythical",
+				Status: 400,
+				Error:  "Didn't get 200 response, body: nope",
+			})).To(BeNil())
+
+			out, err := ioutil.ReadFile(tempFile)
+			Expect(err).To(BeNil())
+			Expect(string(out)).To(Equal(
+				`{"time":"2014-10-17T12:00:00Z","operator":"user@example.com","zone_id":"123","zone_name":"foo.example.com","key":"always_online","before":"off","after":"on","status":200,"config_commit":"abc123"}` + "\n" +
+					`{"time":"2014-10-18T12:00:00Z","operator":"user@example.com","zone_id":"123","zone_name":"foo.example.com","key":"unicorns","before":null,"after":"mythical","status":400,"error":"Didn't get 200 response, body: nope","config_commit":"abc123"}` + "\n",
+			))
+		})
+
+		It("should record changes to a zone's account against the zone", func() {
+			journal.ZoneID = "123"
+
+			Expect(journal.Record(AuditEntry{
+				Time:   day(17),
+				ZoneID: "abc",
+				Key:    "worker script router",
+				After:  "9f86d081884c7d65",
+				Status: 200,
+			})).To(BeNil())
+
+			entries, err := LoadAuditEntries(tempFile, AuditFilter{Zone: "123"})
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].ZoneID).To(Equal("123"))
+			Expect(entries[0].AccountID).To(Equal("abc"))
+		})
+	})
+
+	Describe("LoadAuditEntries()", func() {
+		BeforeEach(func() {
+			for i, key := range []string{"always_online", "ipv6", "always_online"} {
+				Expect(journal.Record(AuditEntry{
+					Time:   day(17 + i),
+					ZoneID: "123",
+					Key:    key,
+					Status: 200,
+				})).To(BeNil())
+			}
+		})
+
+		It("should return every entry with an empty filter", func() {
+			entries, err := LoadAuditEntries(tempFile, AuditFilter{})
+
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(3))
+			Expect(entries[0]).To(Equal(AuditEntry{
+				Time:         day(17),
+				Operator:     "user@example.com",
+				ZoneID:       "123",
+				ZoneName:     "foo.example.com",
+				Key:          "always_online",
+				Status:       200,
+				ConfigCommit: "abc123",
+			}))
+		})
+
+		It("should filter by zone ID or name", func() {
+			entries, err := LoadAuditEntries(tempFile, AuditFilter{Zone: "foo.example.com"})
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(3))
+
+			entries, err = LoadAuditEntries(tempFile, AuditFilter{Zone: "456"})
+			Expect(err).To(BeNil())
+			Expect(entries).To(BeEmpty())
+		})
+
+		It("should filter by key and date range", func() {
+			entries, err := LoadAuditEntries(tempFile, AuditFilter{
+				Key:   "always_online",
+				Since: day(18),
+				Until: day(20),
+			})
+
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Time).To(Equal(day(19)))
+		})
+	})
+})
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// zoneFileDefaultTTL is written as $TTL and used for records with automatic
+// TTL, which CloudFlare represent as a TTL of 1 and currently set to 300.
+const zoneFileDefaultTTL = 300
+
+type ZoneFileError struct {
+	Line    int
+	Message string
+}
+
+func (z ZoneFileError) Error() string {
+	return fmt.Sprintf("line %d: %s", z.Line, z.Message)
+}
+
+// dnsRecordsWithHostnames have a hostname in their content, which must be
+// fully qualified in a zone file.
+var dnsRecordsWithHostnames = map[string]bool{
+	"CNAME": true,
+	"MX":    true,
+	"NS":    true,
+	"PTR":   true,
+	"SRV":   true,
+}
+
+var zoneFileClasses = map[string]bool{
+	"IN": true,
+	"CH": true,
+	"HS": true,
+}
+
+// zoneFileSOATimers are the refresh, retry, expire and minimum values of
+// the SOA records that CloudFlare serve.
+const zoneFileSOATimers = "10000 2400 604800 3600"
+
+// zoneFileNSTTL is the TTL of the NS records that CloudFlare serve for the
+// zone itself.
+const zoneFileNSTTL = 86400
+
+// ExportZoneFile writes DNS records for the zone origin in RFC 1035 format,
+// after an SOA record and NS records for the zone's name servers, which
+// CloudFlare manage. Settings that are specific to CloudFlare are written
+// to comments at the end of each record, eg. "; cf_proxied=true".
+func ExportZoneFile(w io.Writer, origin string, nameServers []string, records ResourceItems) error {
+	origin = strings.TrimSuffix(origin, ".")
+	if len(nameServers) == 0 {
+		return fmt.Errorf("Zone %s has no name servers", origin)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "$ORIGIN %s.\n", origin)
+	fmt.Fprintf(buf, "$TTL %d\n", zoneFileDefaultTTL)
+	fmt.Fprintf(buf, "%s.\t%d\tIN\tSOA\t%s dns.cloudflare.com. 1 %s\n",
+		origin, zoneFileDefaultTTL, fullyQualify(nameServers[0]), zoneFileSOATimers)
+	for _, nameServer := range nameServers {
+		fmt.Fprintf(buf, "%s.\t%d\tIN\tNS\t%s\n", origin, zoneFileNSTTL, fullyQualify(nameServer))
+	}
+
+	records = orderDNSRecords(append(ResourceItems{}, records...))
+	for _, record := range records {
+		recordType := dnsRecordString(record, "type")
+		content := dnsRecordString(record, "content")
+
+		switch {
+		case recordType == "TXT" || recordType == "SPF":
+			content = quoteZoneFileString(content)
+		case dnsRecordsWithHostnames[recordType]:
+			content = fullyQualify(content)
+		}
+
+		if priority, ok := record["priority"].(float64); ok && (recordType == "MX" || recordType == "SRV") {
+			content = fmt.Sprintf("%d %s", int(priority), content)
+		}
+
+		ttl := zoneFileDefaultTTL
+		comments := []string{}
+		if recordTTL, ok := record["ttl"].(float64); ok && recordTTL > 1 {
+			ttl = int(recordTTL)
+		} else {
+			comments = append(comments, "cf_ttl=auto")
+		}
+		if proxied, ok := record["proxied"].(bool); ok && proxied {
+			comments = append(comments, "cf_proxied=true")
+		}
+
+		line := fmt.Sprintf("%s\t%d\tIN\t%s\t%s",
+			fullyQualify(dnsRecordString(record, "name")), ttl, recordType, content)
+		if len(comments) > 0 {
+			line = fmt.Sprintf("%s\t; %s", line, strings.Join(comments, " "))
+		}
+		fmt.Fprintln(buf, line)
+	}
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+func fullyQualify(name string) string {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return name
+	}
+
+	last := fields[len(fields)-1]
+	if !strings.HasSuffix(last, ".") {
+		fields[len(fields)-1] = last + "."
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// quoteZoneFileString quotes a string, splitting it into the 255 byte
+// strings that TXT records are limited to before escaping them.
+func quoteZoneFileString(val string) string {
+	parts := []string{}
+	for {
+		chunk := val
+		if len(chunk) > 255 {
+			chunk = chunk[:255]
+		}
+		val = val[len(chunk):]
+
+		escaped := strings.Replace(strings.Replace(chunk, `\`, `\\`, -1), `"`, `\"`, -1)
+		parts = append(parts, fmt.Sprintf(`"%s"`, escaped))
+
+		if val == "" {
+			break
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ImportZoneFile parses an RFC 1035 zone file into DNS records in the
+// format used by `dns upload`. Records are relative to origin unless the
+// file sets $ORIGIN. SOA records and NS records for the origin are skipped
+// because CloudFlare manage them.
+func ImportZoneFile(r io.Reader, origin string) (ResourceItems, error) {
+	origin = strings.TrimSuffix(origin, ".")
+	records := ResourceItems{}
+
+	var (
+		ttl        = 1
+		owner      string
+		entry      []zoneFileToken
+		entryLine  int
+		parenDepth int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		tokens, depth, err := tokenizeZoneFileLine(line, parenDepth)
+		if err != nil {
+			return nil, ZoneFileError{Line: lineNum, Message: err.Error()}
+		}
+
+		if parenDepth == 0 {
+			entryLine = lineNum
+			entry = nil
+			if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+				entry = append(entry, zoneFileToken{})
+			}
+		}
+		entry = append(entry, tokens...)
+		parenDepth = depth
+		if parenDepth > 0 {
+			continue
+		}
+
+		if len(entry) == 0 || (len(entry) == 1 && entry[0].empty()) {
+			continue
+		}
+
+		fail := func(format string, args ...interface{}) error {
+			return ZoneFileError{Line: entryLine, Message: fmt.Sprintf(format, args...)}
+		}
+
+		switch strings.ToUpper(entry[0].text) {
+		case "$ORIGIN":
+			if len(entry) < 2 {
+				return nil, fail("$ORIGIN requires a name")
+			}
+			origin = strings.TrimSuffix(entry[1].text, ".")
+			continue
+		case "$TTL":
+			if len(entry) < 2 {
+				return nil, fail("$TTL requires a value")
+			}
+			if ttl, err = parseZoneFileTTL(entry[1].text); err != nil {
+				return nil, fail("invalid $TTL %q", entry[1].text)
+			}
+			continue
+		case "$INCLUDE":
+			return nil, fail("$INCLUDE is not supported")
+		}
+
+		if !entry[0].empty() {
+			owner = qualifyZoneFileName(entry[0].text, origin)
+		}
+		if owner == "" {
+			return nil, fail("record has no owner name")
+		}
+
+		record := ResourceItem{"name": owner, "ttl": float64(ttl)}
+		fields := entry[1:]
+		for len(fields) > 0 {
+			if recordTTL, err := parseZoneFileTTL(fields[0].text); err == nil && !fields[0].quoted {
+				record["ttl"] = float64(recordTTL)
+			} else if !zoneFileClasses[strings.ToUpper(fields[0].text)] {
+				break
+			}
+			fields = fields[1:]
+		}
+		if len(fields) < 2 {
+			return nil, fail("record for %q has no type or data", owner)
+		}
+
+		recordType := strings.ToUpper(fields[0].text)
+		data := fields[1:]
+		record["type"] = recordType
+
+		if recordType == "SOA" || (recordType == "NS" && owner == origin) {
+			continue
+		}
+
+		if (recordType == "MX" || recordType == "SRV") && len(data) > 1 {
+			priority, err := strconv.Atoi(data[0].text)
+			if err != nil {
+				return nil, fail("invalid %s priority %q", recordType, data[0].text)
+			}
+			record["priority"] = float64(priority)
+			data = data[1:]
+		}
+
+		switch {
+		case recordType == "TXT" || recordType == "SPF":
+			parts := []string{}
+			for _, token := range data {
+				parts = append(parts, token.text)
+			}
+			record["content"] = strings.Join(parts, "")
+		case dnsRecordsWithHostnames[recordType]:
+			parts := []string{}
+			for _, token := range data[:len(data)-1] {
+				parts = append(parts, token.text)
+			}
+			parts = append(parts, qualifyZoneFileName(data[len(data)-1].text, origin))
+			record["content"] = strings.Join(parts, " ")
+		default:
+			parts := []string{}
+			for _, token := range data {
+				if token.quoted {
+					parts = append(parts, strconv.Quote(token.text))
+				} else {
+					parts = append(parts, token.text)
+				}
+			}
+			record["content"] = strings.Join(parts, " ")
+		}
+
+		for _, token := range entry {
+			for _, comment := range token.comments {
+				switch comment {
+				case "cf_proxied=true":
+					record["proxied"] = true
+				case "cf_proxied=false":
+					record["proxied"] = false
+				case "cf_ttl=auto":
+					record["ttl"] = float64(1)
+				}
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if parenDepth > 0 {
+		return nil, ZoneFileError{Line: entryLine, Message: "unbalanced parentheses"}
+	}
+
+	return records, nil
+}
+
+// zoneFileTTLUnits are the units of TTLs in BIND's format, eg. "1h30m".
+var zoneFileTTLUnits = map[rune]int{
+	's': 1,
+	'm': 60,
+	'h': 60 * 60,
+	'd': 24 * 60 * 60,
+	'w': 7 * 24 * 60 * 60,
+}
+
+// parseZoneFileTTL parses a TTL in seconds, or in BIND's format of numbers
+// followed by units, which are case insensitive.
+func parseZoneFileTTL(val string) (int, error) {
+	if ttl, err := strconv.Atoi(val); err == nil {
+		return ttl, nil
+	}
+
+	ttl, number := 0, ""
+	for _, r := range strings.ToLower(val) {
+		if unicode.IsDigit(r) {
+			number += string(r)
+			continue
+		}
+
+		seconds, ok := zoneFileTTLUnits[r]
+		if !ok || number == "" {
+			return 0, fmt.Errorf("invalid TTL %q", val)
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, err
+		}
+		ttl += n * seconds
+		number = ""
+	}
+	if number != "" || val == "" {
+		return 0, fmt.Errorf("invalid TTL %q", val)
+	}
+
+	return ttl, nil
+}
+
+// qualifyZoneFileName returns a name without a trailing dot, relative
+// names having had the origin appended.
+func qualifyZoneFileName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return strings.TrimSuffix(name, ".")
+	case origin == "":
+		return name
+	}
+
+	return fmt.Sprintf("%s.%s", name, origin)
+}
+
+type zoneFileToken struct {
+	text     string
+	quoted   bool
+	comments []string
+}
+
+// empty tokens stand in for the owner name of records that begin with
+// whitespace, which inherit the owner of the previous record.
+func (z zoneFileToken) empty() bool {
+	return z.text == "" && !z.quoted
+}
+
+// tokenizeZoneFileLine splits a line into tokens, removing parentheses and
+// comments. The comment's space-separated words are attached to the last
+// token. It returns the depth of parentheses at the end of the line.
+func tokenizeZoneFileLine(line string, depth int) ([]zoneFileToken, int, error) {
+	tokens := []zoneFileToken{}
+	current := &bytes.Buffer{}
+	inToken, quoted := false, false
+
+	flush := func() {
+		if inToken {
+			tokens = append(tokens, zoneFileToken{text: current.String(), quoted: quoted})
+		}
+		current.Reset()
+		inToken, quoted = false, false
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			flush()
+			inToken, quoted = true, true
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				current.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, depth, fmt.Errorf("unterminated quoted string")
+			}
+			flush()
+		case c == ';':
+			flush()
+			comments := strings.Fields(line[i+1:])
+			if len(tokens) > 0 {
+				tokens[len(tokens)-1].comments = comments
+			}
+			return tokens, depth, nil
+		case c == '(':
+			flush()
+			depth++
+		case c == ')':
+			flush()
+			if depth == 0 {
+				return nil, depth, fmt.Errorf("unbalanced parentheses")
+			}
+			depth--
+		case unicode.IsSpace(rune(c)):
+			flush()
+		default:
+			if c == '\\' && i+1 < len(line) {
+				i++
+				c = line[i]
+			}
+			inToken = true
+			current.WriteByte(c)
+		}
+	}
+	flush()
+
+	return tokens, depth, nil
+}
@@ -0,0 +1,127 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bytes"
+	"strings"
+)
+
+var _ = Describe("BIND zone files", func() {
+	records := ResourceItems{
+		{"type": "A", "name": "www.example.com", "content": "192.0.2.1", "ttl": float64(1), "proxied": true},
+		{"type": "MX", "name": "example.com", "content": "mx1.example.com", "priority": float64(10), "ttl": float64(3600), "proxied": false},
+		{"type": "TXT", "name": "example.com", "content": `v=spf1 include:"_spf.example.com" -all`, "ttl": float64(3600), "proxied": false},
+		{"type": "CNAME", "name": "cdn.example.com", "content": "www.example.com", "ttl": float64(3600), "proxied": false},
+	}
+
+	nameServers := []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"}
+
+	zoneFile := "$ORIGIN example.com.\n" +
+		"$TTL 300\n" +
+		"example.com.\t300\tIN\tSOA\tada.ns.cloudflare.com. dns.cloudflare.com. 1 10000 2400 604800 3600\n" +
+		"example.com.\t86400\tIN\tNS\tada.ns.cloudflare.com.\n" +
+		"example.com.\t86400\tIN\tNS\tbob.ns.cloudflare.com.\n" +
+		"cdn.example.com.\t3600\tIN\tCNAME\twww.example.com.\n" +
+		"example.com.\t3600\tIN\tMX\t10 mx1.example.com.\n" +
+		"example.com.\t3600\tIN\tTXT\t\"v=spf1 include:\\\"_spf.example.com\\\" -all\"\n" +
+		"www.example.com.\t300\tIN\tA\t192.0.2.1\t; cf_ttl=auto cf_proxied=true\n"
+
+	Describe("ExportZoneFile()", func() {
+		It("should write records in RFC 1035 format with CloudFlare settings in comments", func() {
+			buf := &bytes.Buffer{}
+
+			Expect(ExportZoneFile(buf, "example.com", nameServers, records)).To(BeNil())
+			Expect(buf.String()).To(Equal(zoneFile))
+		})
+
+		It("should split long TXT records into 255 byte strings before escaping them", func() {
+			content := strings.Repeat("a", 254) + `"` + strings.Repeat("b", 254) + `\`
+			buf := &bytes.Buffer{}
+
+			Expect(ExportZoneFile(buf, "example.com", nameServers, ResourceItems{
+				{"type": "TXT", "name": "example.com", "content": content, "ttl": float64(3600), "proxied": false},
+			})).To(BeNil())
+			Expect(buf.String()).To(ContainSubstring("\t\"" + strings.Repeat("a", 254) + `\"" "` + strings.Repeat("b", 254) + `\\"` + "\n"))
+
+			imported, err := ImportZoneFile(buf, "")
+			Expect(err).To(BeNil())
+			Expect(imported).To(Equal(ResourceItems{
+				{"type": "TXT", "name": "example.com", "content": content, "ttl": float64(3600)},
+			}))
+		})
+
+		It("should return an error if the zone has no name servers", func() {
+			Expect(ExportZoneFile(&bytes.Buffer{}, "example.com", nil, records)).To(MatchError("Zone example.com has no name servers"))
+		})
+	})
+
+	Describe("ImportZoneFile()", func() {
+		It("should parse an exported zone file back into the same records", func() {
+			imported, err := ImportZoneFile(strings.NewReader(zoneFile), "")
+
+			Expect(err).To(BeNil())
+			Expect(imported).To(ConsistOf(
+				ResourceItem{"type": "A", "name": "www.example.com", "content": "192.0.2.1", "ttl": float64(1), "proxied": true},
+				ResourceItem{"type": "MX", "name": "example.com", "content": "mx1.example.com", "priority": float64(10), "ttl": float64(3600)},
+				ResourceItem{"type": "TXT", "name": "example.com", "content": `v=spf1 include:"_spf.example.com" -all`, "ttl": float64(3600)},
+				ResourceItem{"type": "CNAME", "name": "cdn.example.com", "content": "www.example.com", "ttl": float64(3600)},
+			))
+		})
+
+		It("should handle relative names, inherited owners, multi-line records and the apex", func() {
+			imported, err := ImportZoneFile(strings.NewReader(`
+$TTL 3600
+@	IN	SOA	ns1.example.com. hostmaster.example.com. (
+		2014101701 ; serial
+		7200 3600 1209600 300 )
+@		IN	NS	ns1.example.com.
+		IN	A	192.0.2.1
+www	600	IN	A	192.0.2.2
+	IN	AAAA	2001:db8::2
+txt	IN	TXT	"part one " "part two"
+`), "example.com.")
+
+			Expect(err).To(BeNil())
+			Expect(imported).To(Equal(ResourceItems{
+				{"type": "A", "name": "example.com", "content": "192.0.2.1", "ttl": float64(3600)},
+				{"type": "A", "name": "www.example.com", "content": "192.0.2.2", "ttl": float64(600)},
+				{"type": "AAAA", "name": "www.example.com", "content": "2001:db8::2", "ttl": float64(3600)},
+				{"type": "TXT", "name": "txt.example.com", "content": "part one part two", "ttl": float64(3600)},
+			}))
+		})
+
+		It("should parse TTLs with units", func() {
+			imported, err := ImportZoneFile(strings.NewReader(`
+$TTL 1h
+www	1d	IN	A	192.0.2.1
+cdn	IN	1h30M	CNAME	www
+	IN	A	192.0.2.2
+api	1W	IN	A	192.0.2.3
+`), "example.com")
+
+			Expect(err).To(BeNil())
+			Expect(imported).To(Equal(ResourceItems{
+				{"type": "A", "name": "www.example.com", "content": "192.0.2.1", "ttl": float64(86400)},
+				{"type": "CNAME", "name": "cdn.example.com", "content": "www.example.com", "ttl": float64(5400)},
+				{"type": "A", "name": "cdn.example.com", "content": "192.0.2.2", "ttl": float64(3600)},
+				{"type": "A", "name": "api.example.com", "content": "192.0.2.3", "ttl": float64(604800)},
+			}))
+		})
+
+		It("should return an error for an invalid $TTL", func() {
+			_, err := ImportZoneFile(strings.NewReader("$TTL 1y\n"), "example.com")
+
+			Expect(err).To(MatchError(`line 1: invalid $TTL "1y"`))
+		})
+
+		It("should return the line number of errors", func() {
+			_, err := ImportZoneFile(strings.NewReader("$ORIGIN example.com.\nwww IN A\n"), "")
+
+			Expect(err).To(MatchError(`line 2: record for "www.example.com" has no type or data`))
+		})
+	})
+})
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// CustomCertificate is a certificate, and private key, that was uploaded
+// for CloudFlare to serve instead of one they issue.
+type CustomCertificate struct {
+	ID           string    `json:"id"`
+	Hosts        []string  `json:"hosts"`
+	Issuer       string    `json:"issuer"`
+	Signature    string    `json:"signature"`
+	Status       string    `json:"status"`
+	BundleMethod string    `json:"bundle_method"`
+	ExpiresOn    time.Time `json:"expires_on"`
+	UploadedOn   time.Time `json:"uploaded_on"`
+}
+
+// DaysToExpiry rounds down, so a certificate that expires in less than a
+// day has 0 days and one that has expired has a negative number.
+func (c CustomCertificate) DaysToExpiry(now time.Time) int {
+	remaining := c.ExpiresOn.Sub(now)
+	days := int(remaining / (24 * time.Hour))
+	if remaining < 0 && remaining%(24*time.Hour) != 0 {
+		days--
+	}
+
+	return days
+}
+
+type CustomCertificateUpload struct {
+	Certificate  string `json:"certificate"`
+	PrivateKey   string `json:"private_key"`
+	BundleMethod string `json:"bundle_method,omitempty"`
+}
+
+// LoadCustomCertificateUpload reads PEM encoded certificate and key files,
+// checking that the key belongs to the certificate before it's uploaded.
+// The certificate file may include intermediate certificates.
+func LoadCustomCertificateUpload(certFile, keyFile, bundleMethod string) (CustomCertificateUpload, error) {
+	var upload CustomCertificateUpload
+
+	cert, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return upload, err
+	}
+
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return upload, err
+	}
+
+	if _, err := tls.X509KeyPair(cert, key); err != nil {
+		return upload, fmt.Errorf("Invalid certificate and key: %s", err)
+	}
+
+	upload = CustomCertificateUpload{
+		Certificate:  string(cert),
+		PrivateKey:   string(key),
+		BundleMethod: bundleMethod,
+	}
+
+	return upload, nil
+}
+
+func (c *CloudFlare) CustomCertificates(zone string) ([]CustomCertificate, error) {
+	var certs []CustomCertificate
+	err := c.allPages(fmt.Sprintf("/zones/%s/custom_certificates", zone), &certs)
+
+	return certs, err
+}
+
+// UploadCustomCertificate uploads a new certificate, or replaces the
+// certificate with the given ID so that it's rotated without CloudFlare
+// ever serving a different one.
+func (c *CloudFlare) UploadCustomCertificate(zone, id string, upload CustomCertificateUpload) (CustomCertificate, error) {
+	var cert CustomCertificate
+
+	method, path := "POST", fmt.Sprintf("/zones/%s/custom_certificates", zone)
+	if id != "" {
+		method, path = "PATCH", fmt.Sprintf("%s/%s", path, id)
+	}
+
+	result, err := c.sendResourceItem(method, path, upload)
+	if err != nil {
+		return cert, err
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return cert, err
+	}
+	err = json.Unmarshal(bs, &cert)
+
+	return cert, err
+}
+
+// WriteCertificateReport writes a line for each certificate and returns
+// those that expire within threshold days of now.
+func WriteCertificateReport(w io.Writer, zone string, certs []CustomCertificate, now time.Time, threshold int) ([]CustomCertificate, error) {
+	expiring := []CustomCertificate{}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, cert := range certs {
+		days := cert.DaysToExpiry(now)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d days", zone, cert.ID, strings.Join(cert.Hosts, ","),
+			cert.Issuer, cert.ExpiresOn.Format("2006-01-02"), days)
+
+		if days <= threshold {
+			expiring = append(expiring, cert)
+			fmt.Fprint(tw, "\tEXPIRING")
+		}
+		fmt.Fprintln(tw)
+	}
+
+	return expiring, tw.Flush()
+}
@@ -0,0 +1,214 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var _ = Describe("Certs", func() {
+	now := time.Date(2014, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	Describe("DaysToExpiry()", func() {
+		It("should round down to whole days", func() {
+			cert := CustomCertificate{ExpiresOn: now.Add(30*24*time.Hour + time.Hour)}
+			Expect(cert.DaysToExpiry(now)).To(Equal(30))
+		})
+
+		It("should be negative for expired certificates", func() {
+			cert := CustomCertificate{ExpiresOn: now.Add(-time.Hour)}
+			Expect(cert.DaysToExpiry(now)).To(Equal(-1))
+		})
+	})
+
+	Describe("WriteCertificateReport()", func() {
+		It("should list certificates and return those expiring within the threshold", func() {
+			certs := []CustomCertificate{
+				{ID: "1", Hosts: []string{"example.com", "www.example.com"}, Issuer: "DigiCert", ExpiresOn: now.Add(90 * 24 * time.Hour)},
+				{ID: "2", Hosts: []string{"old.example.com"}, Issuer: "GlobalSign", ExpiresOn: now.Add(10 * 24 * time.Hour)},
+			}
+			buf := &bytes.Buffer{}
+
+			expiring, err := WriteCertificateReport(buf, "example.com", certs, now, 30)
+
+			Expect(err).To(BeNil())
+			Expect(expiring).To(Equal(certs[1:]))
+			Expect(buf.String()).To(MatchRegexp(`example.com\s+1\s+example.com,www.example.com\s+DigiCert\s+2015-01-15\s+90 days\n`))
+			Expect(buf.String()).To(MatchRegexp(`example.com\s+2\s+old.example.com\s+GlobalSign\s+2014-10-27\s+10 days\s+EXPIRING\n`))
+		})
+	})
+
+	Describe("LoadCustomCertificateUpload()", func() {
+		var dir string
+
+		writePEM := func(name, blockType string, bs []byte) string {
+			file := filepath.Join(dir, name)
+			Expect(ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bs}), 0600)).To(BeNil())
+			return file
+		}
+
+		generateKey := func(name string) (*ecdsa.PrivateKey, string) {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).To(BeNil())
+			bs, err := x509.MarshalECPrivateKey(key)
+			Expect(err).To(BeNil())
+
+			return key, writePEM(name, "EC PRIVATE KEY", bs)
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "cloudflare-configure")
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("should load a certificate and its key", func() {
+			key, keyFile := generateKey("key.pem")
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: "www.example.com"},
+				NotBefore:    now,
+				NotAfter:     now.Add(24 * time.Hour),
+			}
+			der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+			Expect(err).To(BeNil())
+			certFile := writePEM("cert.pem", "CERTIFICATE", der)
+
+			upload, err := LoadCustomCertificateUpload(certFile, keyFile, "ubiquitous")
+
+			Expect(err).To(BeNil())
+			Expect(upload.Certificate).To(ContainSubstring("BEGIN CERTIFICATE"))
+			Expect(upload.PrivateKey).To(ContainSubstring("BEGIN EC PRIVATE KEY"))
+			Expect(upload.BundleMethod).To(Equal("ubiquitous"))
+		})
+
+		It("should return an error when the key doesn't match the certificate", func() {
+			key, _ := generateKey("key.pem")
+			_, otherKeyFile := generateKey("other.pem")
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				NotBefore:    now,
+				NotAfter:     now.Add(24 * time.Hour),
+			}
+			der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+			Expect(err).To(BeNil())
+			certFile := writePEM("cert.pem", "CERTIFICATE", der)
+
+			_, err = LoadCustomCertificateUpload(certFile, otherKeyFile, "")
+
+			Expect(err).To(MatchError(ContainSubstring("Invalid certificate and key")))
+		})
+	})
+
+	Describe("UploadCustomCertificate()", func() {
+		var (
+			server     *ghttp.Server
+			cloudFlare *CloudFlare
+		)
+
+		upload := CustomCertificateUpload{Certificate: "CERT", PrivateKey: "KEY"}
+		response := `{
+			"errors": [],
+			"messages": [],
+			"result": {
+				"id": "2458ce5a-0c35-4c7f-82c7-8e9487d3ff60",
+				"hosts": ["example.com"],
+				"issuer": "GlobalSign",
+				"expires_on": "2016-01-01T05:20:00Z"
+			},
+			"success": true
+		}`
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(gbytes.NewBuffer(), "", 0))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should upload a new certificate", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/custom_certificates"),
+					ghttp.VerifyJSON(`{"certificate": "CERT", "private_key": "KEY"}`),
+					ghttp.RespondWith(http.StatusOK, response),
+				),
+			)
+
+			cert, err := cloudFlare.UploadCustomCertificate("123", "", upload)
+
+			Expect(err).To(BeNil())
+			Expect(cert.ID).To(Equal("2458ce5a-0c35-4c7f-82c7-8e9487d3ff60"))
+			Expect(cert.ExpiresOn).To(Equal(time.Date(2016, 1, 1, 5, 20, 0, 0, time.UTC)))
+		})
+
+		It("should replace an existing certificate in place", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PATCH", "/zones/123/custom_certificates/2458ce5a-0c35-4c7f-82c7-8e9487d3ff60"),
+					ghttp.RespondWith(http.StatusOK, response),
+				),
+			)
+
+			_, err := cloudFlare.UploadCustomCertificate("123", "2458ce5a-0c35-4c7f-82c7-8e9487d3ff60", upload)
+
+			Expect(err).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+	Describe("CustomCertificates()", func() {
+		It("should request every page", func() {
+			server := ghttp.NewServer()
+			defer server.Close()
+			cloudFlare := NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(gbytes.NewBuffer(), "", 0))
+
+			certs := []string{}
+			for i := 0; i < CloudFlarePerPage; i++ {
+				certs = append(certs, fmt.Sprintf(`{"id": "%d", "expires_on": "2016-01-01T05:20:00Z"}`, i))
+			}
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/zones/123/custom_certificates", fmt.Sprintf("page=1&per_page=%d", CloudFlarePerPage)),
+					ghttp.RespondWith(http.StatusOK, fmt.Sprintf(`{"errors": [], "messages": [], "success": true, "result": [%s]}`, strings.Join(certs, ","))),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/zones/123/custom_certificates", fmt.Sprintf("page=2&per_page=%d", CloudFlarePerPage)),
+					ghttp.RespondWith(http.StatusOK, `{"errors": [], "messages": [], "success": true, "result": [{"id": "last", "expires_on": "2014-10-24T05:20:00Z"}]}`),
+				),
+			)
+
+			items, err := cloudFlare.CustomCertificates("123")
+
+			Expect(err).To(BeNil())
+			Expect(items).To(HaveLen(CloudFlarePerPage + 1))
+			Expect(items[CloudFlarePerPage].ID).To(Equal("last"))
+		})
+	})
+})
@@ -7,6 +7,9 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 )
 
 type CloudFlareError struct {
@@ -14,6 +17,26 @@ type CloudFlareError struct {
 	Message string
 }
 
+type CloudFlareHTTPError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (c CloudFlareHTTPError) Error() string {
+	return fmt.Sprintf("Didn't get 200 response, body: %s", c.Body)
+}
+
+// CloudFlareResponseError is returned when a response body indicates failure,
+// even though the response had a 200 status.
+type CloudFlareResponseError struct {
+	StatusCode int
+	Response   CloudFlareResponse
+}
+
+func (c CloudFlareResponseError) Error() string {
+	return fmt.Sprintf("Response body indicated failure, response: %#v", c.Response)
+}
+
 type CloudFlareResponse struct {
 	Success  bool
 	Errors   []CloudFlareError
@@ -22,8 +45,13 @@ type CloudFlareResponse struct {
 }
 
 type CloudFlareZoneItem struct {
-	ID   string
-	Name string
+	ID                  string
+	Name                string
+	Status              string   `json:"status,omitempty"`
+	Paused              bool     `json:"paused,omitempty"`
+	Type                string   `json:"type,omitempty"`
+	NameServers         []string `json:"name_servers,omitempty"`
+	OriginalNameServers []string `json:"original_name_servers,omitempty"`
 }
 
 type CloudFlareSetting struct {
@@ -49,9 +77,10 @@ type CloudFlareRequestItem struct {
 }
 
 type CloudFlare struct {
-	Client *http.Client
-	Query  *CloudFlareQuery
-	log    *log.Logger
+	Client  *http.Client
+	Query   *CloudFlareQuery
+	Journal *AuditJournal
+	log     *log.Logger
 }
 
 func (c *CloudFlare) Set(zone, id string, val interface{}) error {
@@ -98,11 +127,19 @@ func (c *CloudFlare) Update(zone string, config ConfigItemsForUpdate, logOnly bo
 		action = "Changing"
 	}
 
-	for key, vals := range config {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		vals := config[key]
 		c.log.Printf("%s setting %q from %#v to %#v", action, key, vals.Current, vals.Expected)
 
 		if !logOnly {
-			if err := c.Set(zone, key, vals.Expected); err != nil {
+			err := c.Set(zone, key, vals.Expected)
+			if err = c.audit(zone, key, vals.Current, vals.Expected, err); err != nil {
 				return err
 			}
 		}
@@ -111,24 +148,119 @@ func (c *CloudFlare) Update(zone string, config ConfigItemsForUpdate, logOnly bo
 	return nil
 }
 
-func (c *CloudFlare) Zones() ([]CloudFlareZoneItem, error) {
-	req, err := c.Query.NewRequest("GET", "/zones")
+// audit records the outcome of a change in the journal, if there is one. It
+// returns the change's error or, failing that, any error from the journal.
+func (c *CloudFlare) audit(zone, key string, before, after interface{}, err error) error {
+	if c.Journal == nil {
+		return err
+	}
+
+	entry := AuditEntry{
+		Time:   time.Now().UTC(),
+		ZoneID: zone,
+		Key:    key,
+		Before: before,
+		After:  after,
+		Status: http.StatusOK,
+	}
 	if err != nil {
-		return nil, err
+		entry.Status = 0
+		entry.Error = err.Error()
+		switch e := err.(type) {
+		case CloudFlareHTTPError:
+			entry.Status = e.StatusCode
+		case CloudFlareResponseError:
+			entry.Status = e.StatusCode
+		}
+	}
+
+	if journalErr := c.Journal.Record(entry); journalErr != nil && err == nil {
+		return journalErr
+	}
+
+	return err
+}
+
+func (c *CloudFlare) Zone(zoneID string) (CloudFlareZoneItem, error) {
+	var zone CloudFlareZoneItem
+
+	req, err := c.Query.NewRequest("GET", fmt.Sprintf("/zones/%s", zoneID))
+	if err != nil {
+		return zone, err
 	}
 
 	response, err := c.MakeRequest(req)
 	if err != nil {
-		return nil, err
+		return zone, err
 	}
 
+	err = json.Unmarshal(response.Result, &zone)
+
+	return zone, err
+}
+
+// CloudFlarePerPage is the number of zones, or other items that aren't
+// resources, to request per page.
+const CloudFlarePerPage = 50
+
+func (c *CloudFlare) Zones() ([]CloudFlareZoneItem, error) {
 	var zones []CloudFlareZoneItem
-	err = json.Unmarshal(response.Result, &zones)
+	err := c.allPages("/zones", &zones)
 
 	return zones, err
 }
 
+// allPages requests every page of a collection and unmarshals the results
+// of all of them into result, which must be a pointer to a slice.
+func (c *CloudFlare) allPages(path string, result interface{}) error {
+	items := []json.RawMessage{}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	for page := 1; ; page++ {
+		req, err := c.Query.NewRequest("GET", fmt.Sprintf("%s%spage=%d&per_page=%d", path, separator, page, CloudFlarePerPage))
+		if err != nil {
+			return err
+		}
+
+		response, err := c.MakeRequest(req)
+		if err != nil {
+			return err
+		}
+
+		var pageItems []json.RawMessage
+		if err := json.Unmarshal(response.Result, &pageItems); err != nil {
+			return err
+		}
+		items = append(items, pageItems...)
+
+		if len(pageItems) < CloudFlarePerPage {
+			break
+		}
+	}
+
+	bs, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bs, result)
+}
+
 func (c *CloudFlare) MakeRequest(request *http.Request) (*CloudFlareResponse, error) {
+	body, err := c.requestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeResponse(body)
+}
+
+// requestBody makes a request and returns the body of the response, or a
+// CloudFlareHTTPError if the response doesn't have a 200 status.
+func (c *CloudFlare) requestBody(request *http.Request) ([]byte, error) {
 	resp, err := c.Client.Do(request)
 	if err != nil {
 		return nil, err
@@ -141,17 +273,23 @@ func (c *CloudFlare) MakeRequest(request *http.Request) (*CloudFlareResponse, er
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Didn't get 200 response, body: %s", body)
+		return nil, CloudFlareHTTPError{StatusCode: resp.StatusCode, Body: body}
 	}
 
+	return body, nil
+}
+
+// decodeResponse decodes the body of a 200 response, returning an error if
+// the body indicates failure.
+func decodeResponse(body []byte) (*CloudFlareResponse, error) {
 	var response CloudFlareResponse
-	err = json.Unmarshal(body, &response)
+	err := json.Unmarshal(body, &response)
 	if err != nil {
 		return nil, err
 	}
 
 	if !response.Success || len(response.Errors) > 0 {
-		return nil, fmt.Errorf("Response body indicated failure, response: %#v", response)
+		return nil, CloudFlareResponseError{StatusCode: http.StatusOK, Response: response}
 	}
 
 	return &response, err
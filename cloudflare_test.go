@@ -9,8 +9,12 @@ import (
 	"github.com/onsi/gomega/ghttp"
 
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 var _ = Describe("CloudFlare", func() {
@@ -96,6 +100,58 @@ var _ = Describe("CloudFlare", func() {
 		})
 	})
 
+	Describe("Zones() with more than one page", func() {
+		It("should request every page", func() {
+			zones := []string{}
+			for i := 0; i < CloudFlarePerPage; i++ {
+				zones = append(zones, fmt.Sprintf(`{"id": "%d", "name": "example%d.com"}`, i, i))
+			}
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/zones", fmt.Sprintf("page=1&per_page=%d", CloudFlarePerPage)),
+					ghttp.RespondWith(http.StatusOK, fmt.Sprintf(`{"errors": [], "messages": [], "success": true, "result": [%s]}`, strings.Join(zones, ","))),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/zones", fmt.Sprintf("page=2&per_page=%d", CloudFlarePerPage)),
+					ghttp.RespondWith(http.StatusOK, `{"errors": [], "messages": [], "success": true, "result": [{"id": "last", "name": "example.org"}]}`),
+				),
+			)
+
+			items, err := cloudFlare.Zones()
+
+			Expect(err).To(BeNil())
+			Expect(items).To(HaveLen(CloudFlarePerPage + 1))
+			Expect(items[CloudFlarePerPage]).To(Equal(CloudFlareZoneItem{ID: "last", Name: "example.org"}))
+		})
+	})
+
+	Describe("Zone()", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/zones/123"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"errors": [],
+						"messages": [],
+						"result": {"id": "123", "name": "foo"},
+						"success": true
+					}`),
+				),
+			)
+		})
+
+		It("should return one CloudFlareZoneItem", func() {
+			zone, err := cloudFlare.Zone("123")
+
+			Expect(zone).To(Equal(CloudFlareZoneItem{
+				ID:   "123",
+				Name: "foo",
+			}))
+			Expect(err).To(BeNil())
+		})
+	})
+
 	Describe("MakeRequest()", func() {
 		var req *http.Request
 
@@ -208,6 +264,7 @@ var _ = Describe("CloudFlare", func() {
 
 				Expect(resp).To(BeNil())
 				Expect(err).To(MatchError("Didn't get 200 response, body: something invalid"))
+				Expect(err.(CloudFlareHTTPError).StatusCode).To(Equal(http.StatusServiceUnavailable))
 			})
 		})
 	})
@@ -414,5 +471,99 @@ var _ = Describe("CloudFlare", func() {
 				Expect(server.ReceivedRequests()).To(HaveLen(1))
 			})
 		})
+
+		Context("with audit journal", func() {
+			var tempDir string
+
+			BeforeEach(func() {
+				var err error
+				tempDir, err = ioutil.TempDir("", "cloudflare-configure")
+				Expect(err).To(BeNil())
+
+				cloudFlare.Journal = &AuditJournal{
+					File:     filepath.Join(tempDir, "audit.jsonl"),
+					Operator: "user@example.com",
+					ZoneName: "foo.example.com",
+				}
+
+				server.RouteToHandler("PATCH", fmt.Sprintf("/zones/%s/settings/always_online", zoneID),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				)
+				server.RouteToHandler("PATCH", fmt.Sprintf("/zones/%s/settings/unicorns", zoneID),
+					ghttp.RespondWith(http.StatusBadRequest, "bad request"),
+				)
+				server.RouteToHandler("PATCH", fmt.Sprintf("/zones/%s/settings/waf", zoneID),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: false}),
+				)
+			})
+
+			AfterEach(func() {
+				err := os.RemoveAll(tempDir)
+				Expect(err).To(BeNil())
+			})
+
+			It("should record applied and failed changes", func() {
+				config := ConfigItemsForUpdate{
+					"always_online": ConfigItemForUpdate{
+						Current:  "off",
+						Expected: settingValAlwaysOnline,
+					},
+					"unicorns": ConfigItemForUpdate{
+						Current:  nil,
+						Expected: "mythical",
+					},
+				}
+
+				Expect(cloudFlare.Update(zoneID, config, false)).ToNot(BeNil())
+
+				entries, err := LoadAuditEntries(cloudFlare.Journal.File, AuditFilter{})
+				Expect(err).To(BeNil())
+				Expect(entries).To(HaveLen(2))
+
+				Expect(entries[0].ZoneID).To(Equal(zoneID))
+				Expect(entries[0].ZoneName).To(Equal("foo.example.com"))
+				Expect(entries[0].Operator).To(Equal("user@example.com"))
+				Expect(entries[0].Key).To(Equal("always_online"))
+				Expect(entries[0].Before).To(Equal("off"))
+				Expect(entries[0].After).To(Equal(settingValAlwaysOnline))
+				Expect(entries[0].Status).To(Equal(http.StatusOK))
+				Expect(entries[0].Error).To(BeEmpty())
+
+				Expect(entries[1].Key).To(Equal("unicorns"))
+				Expect(entries[1].Status).To(Equal(http.StatusBadRequest))
+				Expect(entries[1].Error).To(Equal("Didn't get 200 response, body: bad request"))
+			})
+
+			It("should record the HTTP status of responses that indicate failure", func() {
+				config := ConfigItemsForUpdate{
+					"waf": ConfigItemForUpdate{
+						Current:  "off",
+						Expected: "on",
+					},
+				}
+
+				Expect(cloudFlare.Update(zoneID, config, false)).ToNot(BeNil())
+
+				entries, err := LoadAuditEntries(cloudFlare.Journal.File, AuditFilter{})
+				Expect(err).To(BeNil())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Status).To(Equal(http.StatusOK))
+				Expect(entries[0].Error).To(ContainSubstring("Response body indicated failure"))
+			})
+
+			It("should not record anything when logOnly is true", func() {
+				config := ConfigItemsForUpdate{
+					"always_online": ConfigItemForUpdate{
+						Current:  "off",
+						Expected: settingValAlwaysOnline,
+					},
+				}
+
+				Expect(cloudFlare.Update(zoneID, config, true)).To(BeNil())
+
+				_, err := os.Stat(cloudFlare.Journal.File)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
 	})
 })
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// CustomHostnamesResource manages a zone's custom hostnames, which let
+// customers point their own domains at the zone. Hostnames are identified
+// by themselves, so changing one deletes it and creates another.
+var CustomHostnamesResource = ResourceType{
+	Name:    "custom hostname",
+	Path:    "/zones/%s/custom_hostnames",
+	Key:     customHostnameKey,
+	PerPage: 50,
+	ReadOnly: []string{
+		"id", "status", "created_at", "verification_errors",
+		"ownership_verification", "ownership_verification_http",
+		"ssl.id", "ssl.status", "ssl.validation_records", "ssl.validation_errors",
+		"ssl.hosts", "ssl.issuer", "ssl.serial_number", "ssl.signature",
+		"ssl.uploaded_on", "ssl.expires_on", "ssl.txt_name", "ssl.txt_value",
+		"ssl.http_url", "ssl.http_body", "ssl.cname", "ssl.cname_target",
+	},
+	CreateOnly:   []string{"hostname"},
+	UpdateMethod: "PATCH",
+}
+
+func customHostnameKey(item ResourceItem) string {
+	hostname, _ := item["hostname"].(string)
+	return hostname
+}
+
+// WriteCustomHostnameReport writes the status of each hostname and of its
+// certificate, followed by the DNS records that the customer must create
+// to prove that they own the hostname and for the certificate to be issued.
+func WriteCustomHostnameReport(w io.Writer, items ResourceItems) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\tstatus: %s\tssl: %s\n", customHostnameKey(item),
+			resourceString(item, "status"), resourceString(item, "ssl", "status"))
+
+		if name := resourceString(item, "ownership_verification", "name"); name != "" {
+			fmt.Fprintf(tw, "  ownership\t%s\t%s %s\n", name,
+				resourceString(item, "ownership_verification", "type"),
+				resourceString(item, "ownership_verification", "value"))
+		}
+
+		ssl, _ := item["ssl"].(map[string]interface{})
+		records, _ := ssl["validation_records"].([]interface{})
+		if len(records) == 0 && resourceString(item, "ssl", "txt_name") != "" {
+			records = []interface{}{ssl}
+		}
+		for _, record := range records {
+			record, _ := record.(map[string]interface{})
+			if name := resourceString(record, "txt_name"); name != "" {
+				fmt.Fprintf(tw, "  certificate\t%s\tTXT %s\n", name, resourceString(record, "txt_value"))
+			}
+		}
+
+		for _, problems := range []interface{}{item["verification_errors"], ssl["validation_errors"]} {
+			list, _ := problems.([]interface{})
+			for _, problem := range list {
+				message, ok := problem.(string)
+				if !ok {
+					obj, _ := problem.(map[string]interface{})
+					message = resourceString(obj, "message")
+				}
+				fmt.Fprintf(tw, "  error\t%s\n", message)
+			}
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,65 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bytes"
+)
+
+var _ = Describe("CustomHostnames", func() {
+	pending := ResourceItem{
+		"id":       "0d89c70d-ad9f-4843-b99f-6cc0252067e9",
+		"hostname": "app.customer.com",
+		"status":   "pending",
+		"ssl": map[string]interface{}{
+			"id":     "3f7e5ad0-56b2-4d5e-9d6b-f9e1c4a8e2d1",
+			"status": "pending_validation",
+			"method": "txt",
+			"type":   "dv",
+			"validation_records": []interface{}{
+				map[string]interface{}{
+					"txt_name":  "_acme-challenge.app.customer.com",
+					"txt_value": "810b7d5f01154524b961ba0cd578acc2",
+				},
+			},
+			"settings": map[string]interface{}{"min_tls_version": "1.2"},
+		},
+		"custom_metadata": map[string]interface{}{"customer_id": "12345"},
+		"ownership_verification": map[string]interface{}{
+			"type":  "txt",
+			"name":  "_cf-custom-hostname.app.customer.com",
+			"value": "5cc07c04-ea62-4a5a-95f0-419334a875a4",
+		},
+		"verification_errors": []interface{}{"custom hostname does not CNAME to this zone."},
+	}
+
+	It("should only download the fields that can be set", func() {
+		Expect(CustomHostnamesResource.Strip(ResourceItems{pending})).To(Equal(ResourceItems{
+			{
+				"hostname": "app.customer.com",
+				"ssl": map[string]interface{}{
+					"method":   "txt",
+					"type":     "dv",
+					"settings": map[string]interface{}{"min_tls_version": "1.2"},
+				},
+				"custom_metadata": map[string]interface{}{"customer_id": "12345"},
+			},
+		}))
+	})
+
+	Describe("WriteCustomHostnameReport()", func() {
+		It("should write status and the records that customers must create", func() {
+			buf := &bytes.Buffer{}
+
+			Expect(WriteCustomHostnameReport(buf, ResourceItems{pending})).To(BeNil())
+
+			Expect(buf.String()).To(MatchRegexp(`app.customer.com\s+status: pending\s+ssl: pending_validation\n`))
+			Expect(buf.String()).To(MatchRegexp(`ownership\s+_cf-custom-hostname.app.customer.com\s+txt 5cc07c04-ea62-4a5a-95f0-419334a875a4\n`))
+			Expect(buf.String()).To(MatchRegexp(`certificate\s+_acme-challenge.app.customer.com\s+TXT 810b7d5f01154524b961ba0cd578acc2\n`))
+			Expect(buf.String()).To(MatchRegexp(`error\s+custom hostname does not CNAME to this zone.\n`))
+		})
+	})
+})
@@ -0,0 +1,21 @@
+package main
+
+// CustomPagesResource manages a zone's custom pages, such as those shown
+// for 500 errors or when a request is blocked. CloudFlare have a fixed set
+// of pages, identified by their ID, eg. "500_errors", so pages can't be
+// created or deleted. Pages that aren't in the file are left alone, and a
+// page is returned to CloudFlare's default by setting its state to
+// "default".
+var CustomPagesResource = ResourceType{
+	Name:         "custom page",
+	Path:         "/zones/%s/custom_pages",
+	Key:          customPageKey,
+	Fixed:        true,
+	ReadOnly:     []string{"description", "required_tokens", "preview_target", "created_on", "modified_on"},
+	UpdateMethod: "PUT",
+}
+
+func customPageKey(item ResourceItem) string {
+	id, _ := item["id"].(string)
+	return id
+}
@@ -0,0 +1,53 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CustomPagesResource", func() {
+	current := ResourceItems{
+		{
+			"id":              "500_errors",
+			"description":     "500 Class Errors",
+			"required_tokens": []interface{}{"::CLOUDFLARE_ERROR_500S_BOX::"},
+			"url":             "",
+			"state":           "default",
+		},
+		{
+			"id":              "waf_block",
+			"description":     "WAF Block",
+			"required_tokens": []interface{}{"::CLOUDFLARE_ERROR_1000S_BOX::"},
+			"url":             "https://errors.example.com/block.html",
+			"state":           "customized",
+		},
+	}
+
+	It("should keep the ID when downloading", func() {
+		Expect(CustomPagesResource.Strip(current[:1])).To(Equal(ResourceItems{
+			{"id": "500_errors", "url": "", "state": "default"},
+		}))
+	})
+
+	It("should update pages and leave pages that aren't expected alone", func() {
+		update, err := CompareResourceItemsForUpdate(CustomPagesResource, current, ResourceItems{
+			{"id": "500_errors", "url": "https://errors.example.com/500.html", "state": "customized"},
+		})
+
+		Expect(err).To(BeNil())
+		Expect(update).To(HaveLen(1))
+		Expect(update[0].Action()).To(Equal("update"))
+		Expect(update[0].Key).To(Equal("500_errors"))
+	})
+
+	It("should return an error for pages that don't exist", func() {
+		update, err := CompareResourceItemsForUpdate(CustomPagesResource, current, ResourceItems{
+			{"id": "500_error", "state": "customized"},
+		})
+
+		Expect(update).To(BeNil())
+		Expect(err).To(MatchError(`Unknown custom page "500_error"`))
+	})
+})
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DNSRecordsResource manages a zone's DNS records. Records are identified
+// by their type and name, and also by their content where more than one
+// record has the same type and name, such as round-robin A records or MX
+// records, so that each of those records can be matched individually.
+var DNSRecordsResource = ResourceType{
+	Name:         "DNS record",
+	Path:         "/zones/%s/dns_records",
+	Key:          dnsRecordContentKey,
+	Keys:         dnsRecordKeys,
+	ReadOnly:     []string{"id", "proxiable", "locked", "zone_id", "zone_name", "created_on", "modified_on", "meta"},
+	UpdateMethod: "PATCH",
+	PerPage:      100,
+	Order:        orderDNSRecords,
+}
+
+func dnsRecordString(item ResourceItem, field string) string {
+	val, _ := item[field].(string)
+	return val
+}
+
+func dnsRecordNameKey(item ResourceItem) string {
+	return fmt.Sprintf("%s %s", dnsRecordString(item, "type"), strings.ToLower(dnsRecordString(item, "name")))
+}
+
+func dnsRecordContentKey(item ResourceItem) string {
+	return fmt.Sprintf("%s %s", dnsRecordNameKey(item), dnsRecordString(item, "content"))
+}
+
+// dnsRecordKeys identifies records by type and name unless either the
+// current or expected records have more than one with the same type and
+// name, in which case content is used as well.
+func dnsRecordKeys(current, expected ResourceItems) func(ResourceItem) string {
+	multiple := map[string]bool{}
+	for _, items := range []ResourceItems{current, expected} {
+		seen := map[string]bool{}
+		for _, item := range items {
+			key := dnsRecordNameKey(item)
+			if seen[key] {
+				multiple[key] = true
+			}
+			seen[key] = true
+		}
+	}
+
+	return func(item ResourceItem) string {
+		if multiple[dnsRecordNameKey(item)] {
+			return dnsRecordContentKey(item)
+		}
+
+		return dnsRecordNameKey(item)
+	}
+}
+
+type dnsRecordsByName ResourceItems
+
+func (d dnsRecordsByName) Len() int      { return len(d) }
+func (d dnsRecordsByName) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d dnsRecordsByName) Less(i, j int) bool {
+	iName, jName := strings.ToLower(dnsRecordString(d[i], "name")), strings.ToLower(dnsRecordString(d[j], "name"))
+	if iName != jName {
+		return iName < jName
+	}
+
+	return dnsRecordContentKey(d[i]) < dnsRecordContentKey(d[j])
+}
+
+func orderDNSRecords(items ResourceItems) ResourceItems {
+	sort.Sort(dnsRecordsByName(items))
+	return items
+}
@@ -0,0 +1,76 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DNSRecordsResource", func() {
+	record := func(id, recordType, name, content string) ResourceItem {
+		return ResourceItem{
+			"id":      id,
+			"type":    recordType,
+			"name":    name,
+			"content": content,
+			"proxied": false,
+			"ttl":     float64(1),
+		}
+	}
+
+	current := ResourceItems{
+		record("1", "A", "www.example.com", "192.0.2.1"),
+		record("2", "MX", "example.com", "mx1.example.com"),
+		record("3", "MX", "example.com", "mx2.example.com"),
+	}
+
+	It("should update a record whose type and name are unique", func() {
+		update, err := CompareResourceItemsForUpdate(DNSRecordsResource, current, ResourceItems{
+			{"type": "A", "name": "WWW.example.com", "content": "192.0.2.2", "proxied": true},
+			{"type": "MX", "name": "example.com", "content": "mx1.example.com"},
+			{"type": "MX", "name": "example.com", "content": "mx2.example.com"},
+		})
+
+		Expect(err).To(BeNil())
+		Expect(update).To(HaveLen(1))
+		Expect(update[0].Action()).To(Equal("update"))
+		Expect(update[0].Key).To(Equal("A www.example.com"))
+
+		_, to := update[0].ChangedFields()
+		Expect(to).To(Equal(ResourceItem{
+			"name":    "WWW.example.com",
+			"content": "192.0.2.2",
+			"proxied": true,
+		}))
+	})
+
+	It("should match records with the same type and name by content", func() {
+		update, err := CompareResourceItemsForUpdate(DNSRecordsResource, current, ResourceItems{
+			{"type": "A", "name": "www.example.com", "content": "192.0.2.1"},
+			{"type": "MX", "name": "example.com", "content": "mx2.example.com"},
+			{"type": "MX", "name": "example.com", "content": "mx3.example.com"},
+		})
+
+		Expect(err).To(BeNil())
+		Expect(update).To(HaveLen(2))
+		Expect(update[0].Action()).To(Equal("create"))
+		Expect(update[0].Key).To(Equal("MX example.com mx3.example.com"))
+		Expect(update[1].Action()).To(Equal("delete"))
+		Expect(update[1].Key).To(Equal("MX example.com mx1.example.com"))
+	})
+
+	It("should order downloaded records by name, type and content", func() {
+		items := DNSRecordsResource.Order(ResourceItems{
+			record("1", "A", "www.example.com", "192.0.2.1"),
+			record("3", "MX", "example.com", "mx2.example.com"),
+			record("2", "MX", "example.com", "mx1.example.com"),
+		})
+
+		Expect(DNSRecordsResource.Strip(items)).To(Equal(ResourceItems{
+			{"type": "MX", "name": "example.com", "content": "mx1.example.com", "proxied": false, "ttl": float64(1)},
+			{"type": "MX", "name": "example.com", "content": "mx2.example.com", "proxied": false, "ttl": float64(1)},
+			{"type": "A", "name": "www.example.com", "content": "192.0.2.1", "proxied": false, "ttl": float64(1)},
+		}))
+	})
+})
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AccessRulesResource manages a zone's IP access rules, which allow, block
+// or challenge requests by IP address, range, ASN or country. Rules are
+// identified by what they match, eg. "ip 192.0.2.1" or "country GB". Rules
+// inherited from the account or user aren't managed.
+var AccessRulesResource = ResourceType{
+	Name:         "access rule",
+	Path:         "/zones/%s/firewall/access_rules/rules",
+	Key:          accessRuleKey,
+	PerPage:      100,
+	Filter:       accessRuleInZone,
+	ReadOnly:     []string{"id", "allowed_modes", "scope", "created_on", "modified_on"},
+	CreateOnly:   []string{"configuration"},
+	UpdateMethod: "PATCH",
+}
+
+func accessRuleKey(item ResourceItem) string {
+	configuration, _ := item["configuration"].(map[string]interface{})
+	target, _ := configuration["target"].(string)
+	value, _ := configuration["value"].(string)
+
+	return fmt.Sprintf("%s %s", target, value)
+}
+
+func accessRuleInZone(item ResourceItem) bool {
+	scope, ok := item["scope"].(map[string]interface{})
+	if !ok {
+		return true
+	}
+
+	return scope["type"] == "zone"
+}
+
+// LockdownsResource manages a zone's lockdown rules, which only allow
+// requests for URL patterns from certain IP addresses and ranges. Rules are
+// identified by their description, or by their URL patterns if they don't
+// have one.
+var LockdownsResource = ResourceType{
+	Name:         "zone lockdown",
+	Path:         "/zones/%s/firewall/lockdowns",
+	Key:          lockdownKey,
+	PerPage:      100,
+	ReadOnly:     []string{"id", "created_on", "modified_on"},
+	UpdateMethod: "PUT",
+}
+
+func lockdownKey(item ResourceItem) string {
+	if description, ok := item["description"].(string); ok && description != "" {
+		return description
+	}
+
+	urls, _ := item["urls"].([]interface{})
+	patterns := []string{}
+	for _, url := range urls {
+		if pattern, ok := url.(string); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return strings.Join(patterns, " ")
+}
+
+// UserAgentRulesResource manages a zone's user-agent rules, which block or
+// challenge requests with a User-Agent header. Rules are identified by the
+// user-agent that they match.
+var UserAgentRulesResource = ResourceType{
+	Name:         "user-agent rule",
+	Path:         "/zones/%s/firewall/ua_rules",
+	Key:          userAgentRuleKey,
+	PerPage:      100,
+	ReadOnly:     []string{"id"},
+	UpdateMethod: "PUT",
+}
+
+func userAgentRuleKey(item ResourceItem) string {
+	configuration, _ := item["configuration"].(map[string]interface{})
+	value, _ := configuration["value"].(string)
+
+	return value
+}
+
+// FirewallRulesResource manages a zone's firewall rules, which take an
+// action for requests that match a filter expression. Rules are identified
+// by their description, or by their filter expression if they don't have
+// one.
+var FirewallRulesResource = ResourceType{
+	Name:         "firewall rule",
+	Path:         "/zones/%s/firewall/rules",
+	Key:          firewallRuleKey,
+	PerPage:      100,
+	ReadOnly:     []string{"id", "created_on", "modified_on", "filter.id"},
+	CreateInList: true,
+	UpdateMethod: "PUT",
+	Update:       updateFirewallRule,
+	DeleteParams: "delete_filter_if_unused=true",
+}
+
+func firewallRuleKey(item ResourceItem) string {
+	if description, ok := item["description"].(string); ok && description != "" {
+		return description
+	}
+
+	return resourceString(item, "filter", "expression")
+}
+
+// updateFirewallRule updates the rule's filter, which is a separate object
+// that the rule refers to by ID, before updating the rule itself.
+func updateFirewallRule(c *CloudFlare, zone string, current, item ResourceItem) error {
+	id := resourceID(current)
+	currentFilter, _ := current["filter"].(map[string]interface{})
+	filter, _ := item["filter"].(map[string]interface{})
+	item["id"] = id
+
+	if filter != nil && currentFilter != nil {
+		filter["id"] = currentFilter["id"]
+
+		if !resourceValueMatches(currentFilter, filter) {
+			filterPath := fmt.Sprintf("/zones/%s/filters/%s", zone, filter["id"])
+			if _, err := c.sendResourceItem("PUT", filterPath, ResourceItem(filter)); err != nil {
+				return err
+			}
+		}
+	}
+
+	rulePath := fmt.Sprintf("/zones/%s/firewall/rules/%s", zone, id)
+	_, err := c.sendResourceItem("PUT", rulePath, item)
+
+	return err
+}
@@ -0,0 +1,193 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"log"
+	"net/http"
+)
+
+var _ = Describe("Firewall", func() {
+	Describe("AccessRulesResource", func() {
+		accessRule := func(target, value, scope string) ResourceItem {
+			return ResourceItem{
+				"id":   "92f17202ed8bd63d69a66b86a49a8f6b",
+				"mode": "block",
+				"configuration": map[string]interface{}{
+					"target": target,
+					"value":  value,
+				},
+				"scope": map[string]interface{}{
+					"id":   "7c5dae5552338874e5053f2534d2767a",
+					"type": scope,
+				},
+			}
+		}
+
+		It("should key rules by their target and value", func() {
+			Expect(AccessRulesResource.Key(accessRule("ip", "192.0.2.1", "zone"))).To(Equal("ip 192.0.2.1"))
+			Expect(AccessRulesResource.Key(accessRule("country", "GB", "zone"))).To(Equal("country GB"))
+		})
+
+		It("should only manage rules belonging to the zone", func() {
+			Expect(AccessRulesResource.Filter(accessRule("ip", "192.0.2.1", "zone"))).To(BeTrue())
+			Expect(AccessRulesResource.Filter(accessRule("ip", "192.0.2.1", "organization"))).To(BeFalse())
+		})
+	})
+
+	Describe("LockdownsResource", func() {
+		It("should key rules by their description", func() {
+			Expect(LockdownsResource.Key(ResourceItem{
+				"description": "Admin from office",
+				"urls":        []interface{}{"example.com/admin*"},
+			})).To(Equal("Admin from office"))
+		})
+
+		It("should key rules without a description by their URL patterns", func() {
+			Expect(LockdownsResource.Key(ResourceItem{
+				"urls": []interface{}{"example.com/admin*", "example.com/login"},
+				"configurations": []interface{}{
+					map[string]interface{}{"target": "ip_range", "value": "192.0.2.0/24"},
+				},
+			})).To(Equal("example.com/admin* example.com/login"))
+		})
+	})
+
+	Describe("UserAgentRulesResource", func() {
+		It("should key rules by the user-agent that they match", func() {
+			Expect(UserAgentRulesResource.Key(ResourceItem{
+				"mode": "block",
+				"configuration": map[string]interface{}{
+					"target": "ua",
+					"value":  "BadBot/1.0",
+				},
+			})).To(Equal("BadBot/1.0"))
+		})
+	})
+
+	Describe("FirewallRulesResource", func() {
+		var (
+			server     *ghttp.Server
+			cloudFlare *CloudFlare
+		)
+
+		current := ResourceItem{
+			"id":          "372e67954025e0ba6aaa6d586b9e0b60",
+			"description": "Block bad bots",
+			"action":      "block",
+			"filter": map[string]interface{}{
+				"id":         "4ae338944d6143378c3cf05a7c77d983",
+				"expression": "cf.client.bot",
+			},
+		}
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(gbytes.NewBuffer(), "", 0))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should key rules by their description", func() {
+			Expect(FirewallRulesResource.Key(current)).To(Equal("Block bad bots"))
+		})
+
+		It("should key rules without a description by their filter expression", func() {
+			Expect(FirewallRulesResource.Key(ResourceItem{
+				"id":          "372e67954025e0ba6aaa6d586b9e0b60",
+				"description": "",
+				"action":      "block",
+				"filter": map[string]interface{}{
+					"id":         "4ae338944d6143378c3cf05a7c77d983",
+					"expression": "ip.src eq 192.0.2.1",
+				},
+			})).To(Equal("ip.src eq 192.0.2.1"))
+		})
+
+		It("should update the filter before the rule when the expression changes", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/zones/123/filters/4ae338944d6143378c3cf05a7c77d983"),
+					ghttp.VerifyJSON(`{"id": "4ae338944d6143378c3cf05a7c77d983", "expression": "cf.client.bot and ip.src ne 192.0.2.1"}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/zones/123/firewall/rules/372e67954025e0ba6aaa6d586b9e0b60"),
+					ghttp.VerifyJSON(`{
+						"id": "372e67954025e0ba6aaa6d586b9e0b60",
+						"description": "Block bad bots",
+						"action": "challenge",
+						"filter": {
+							"id": "4ae338944d6143378c3cf05a7c77d983",
+							"expression": "cf.client.bot and ip.src ne 192.0.2.1"
+						}
+					}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			update := ResourceItemsForUpdate{
+				{
+					Key:     "Block bad bots",
+					Current: current,
+					Expected: ResourceItem{
+						"description": "Block bad bots",
+						"action":      "challenge",
+						"filter": map[string]interface{}{
+							"expression": "cf.client.bot and ip.src ne 192.0.2.1",
+						},
+					},
+				},
+			}
+
+			Expect(cloudFlare.UpdateResource(FirewallRulesResource, "123", update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+
+		It("should only update the rule when the filter is unchanged", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/zones/123/firewall/rules/372e67954025e0ba6aaa6d586b9e0b60"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			update := ResourceItemsForUpdate{
+				{
+					Key:     "Block bad bots",
+					Current: current,
+					Expected: ResourceItem{
+						"description": "Block bad bots",
+						"action":      "challenge",
+						"filter":      map[string]interface{}{"expression": "cf.client.bot"},
+					},
+				},
+			}
+
+			Expect(cloudFlare.UpdateResource(FirewallRulesResource, "123", update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("should delete the rule's filter if it's unused", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/zones/123/firewall/rules/372e67954025e0ba6aaa6d586b9e0b60",
+						"delete_filter_if_unused=true"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			update := ResourceItemsForUpdate{{Key: "Block bad bots", Current: current}}
+
+			Expect(cloudFlare.UpdateResource(FirewallRulesResource, "123", update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+})
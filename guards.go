@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ProtectedKeysChanged struct {
+	Keys []string
+}
+
+func (p ProtectedKeysChanged) Error() string {
+	return fmt.Sprintf("Refusing to change protected settings without --allow-protected: %s",
+		strings.Join(p.Keys, ", "))
+}
+
+type TooManyChanges struct {
+	Changes int
+	Limit   int
+}
+
+func (t TooManyChanges) Error() string {
+	return fmt.Sprintf("Refusing to make %d changes, which exceeds --max-changes of %d",
+		t.Changes, t.Limit)
+}
+
+// CheckProtectedKeys returns an error listing any protected keys that would
+// be changed by the update and haven't been explicitly allowed.
+func CheckProtectedKeys(update ConfigItemsForUpdate, protected, allowed []string) error {
+	allowedSet := map[string]bool{}
+	for _, key := range allowed {
+		allowedSet[key] = true
+	}
+
+	keys := []string{}
+	for _, key := range protected {
+		if _, ok := update[key]; ok && !allowedSet[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return ProtectedKeysChanged{Keys: keys}
+	}
+
+	return nil
+}
+
+// CheckMaxChanges returns an error if the update contains more than limit
+// changes. A limit of zero or less disables the check.
+func CheckMaxChanges(update ConfigItemsForUpdate, limit int) error {
+	if limit > 0 && len(update) > limit {
+		return TooManyChanges{Changes: len(update), Limit: limit}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Guards", func() {
+	update := ConfigItemsForUpdate{
+		"ssl": ConfigItemForUpdate{
+			Current:  "full",
+			Expected: "strict",
+		},
+		"waf": ConfigItemForUpdate{
+			Current:  "on",
+			Expected: "off",
+		},
+		"browser_cache_ttl": ConfigItemForUpdate{
+			Current:  14400,
+			Expected: 7200,
+		},
+	}
+
+	Describe("CheckProtectedKeys()", func() {
+		protected := []string{"ssl", "security_level", "waf"}
+
+		It("should return an error listing protected keys that are changing", func() {
+			err := CheckProtectedKeys(update, protected, []string{})
+
+			Expect(err).To(MatchError(ProtectedKeysChanged{Keys: []string{"ssl", "waf"}}))
+			Expect(err.Error()).To(Equal(
+				"Refusing to change protected settings without --allow-protected: ssl, waf"))
+		})
+
+		It("should not return an error for keys that have been allowed", func() {
+			err := CheckProtectedKeys(update, protected, []string{"waf"})
+			Expect(err).To(MatchError(ProtectedKeysChanged{Keys: []string{"ssl"}}))
+
+			err = CheckProtectedKeys(update, protected, []string{"ssl", "waf"})
+			Expect(err).To(BeNil())
+		})
+
+		It("should not return an error when nothing is protected", func() {
+			Expect(CheckProtectedKeys(update, nil, nil)).To(BeNil())
+		})
+	})
+
+	Describe("CheckMaxChanges()", func() {
+		It("should return an error when the update exceeds the limit", func() {
+			Expect(CheckMaxChanges(update, 2)).To(MatchError(
+				"Refusing to make 3 changes, which exceeds --max-changes of 2"))
+		})
+
+		It("should not return an error at or below the limit", func() {
+			Expect(CheckMaxChanges(update, 3)).To(BeNil())
+		})
+
+		It("should not return an error when the limit is disabled", func() {
+			Expect(CheckMaxChanges(update, 0)).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// HealthchecksResource manages a zone's standalone healthchecks, which
+// probe an origin address from one or more regions and notify when it
+// becomes unhealthy. Healthchecks are identified by their name.
+var HealthchecksResource = ResourceType{
+	Name:         "healthcheck",
+	Path:         "/zones/%s/healthchecks",
+	Key:          healthcheckKey,
+	PerPage:      50,
+	ReadOnly:     []string{"id", "status", "failure_reason", "created_on", "modified_on"},
+	UpdateMethod: "PUT",
+}
+
+func healthcheckKey(item ResourceItem) string {
+	name, _ := item["name"].(string)
+	return name
+}
+
+// WriteHealthcheckReport writes the current status of each healthcheck,
+// followed by the reason that it's failing if it's unhealthy.
+func WriteHealthcheckReport(w io.Writer, items ResourceItems) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	for _, item := range items {
+		address, _ := item["address"].(string)
+		status, _ := item["status"].(string)
+		if suspended, _ := item["suspended"].(bool); suspended {
+			status = "suspended"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\tstatus: %s", healthcheckKey(item), address, status)
+		if reason, _ := item["failure_reason"].(string); reason != "" && status != "healthy" {
+			fmt.Fprintf(tw, "\t%s", reason)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
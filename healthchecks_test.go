@@ -0,0 +1,70 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bytes"
+)
+
+var _ = Describe("Healthchecks", func() {
+	healthcheck := ResourceItem{
+		"id":             "699d98642c564d2e855e9661899b7252",
+		"name":           "origin-primary",
+		"address":        "origin.example.com",
+		"type":           "HTTPS",
+		"interval":       float64(60),
+		"check_regions":  []interface{}{"WEU", "EEU"},
+		"http_config":    map[string]interface{}{"path": "/health", "expected_codes": []interface{}{"200"}},
+		"status":         "healthy",
+		"failure_reason": "",
+		"created_on":     "2014-01-01T05:20:00.12345Z",
+		"modified_on":    "2014-01-01T05:20:00.12345Z",
+	}
+
+	It("should key healthchecks by their name", func() {
+		Expect(HealthchecksResource.Key(healthcheck)).To(Equal("origin-primary"))
+	})
+
+	It("should not download status", func() {
+		Expect(HealthchecksResource.Strip(ResourceItems{healthcheck})).To(Equal(ResourceItems{
+			{
+				"name":          "origin-primary",
+				"address":       "origin.example.com",
+				"type":          "HTTPS",
+				"interval":      float64(60),
+				"check_regions": []interface{}{"WEU", "EEU"},
+				"http_config":   map[string]interface{}{"path": "/health", "expected_codes": []interface{}{"200"}},
+			},
+		}))
+	})
+
+	Describe("WriteHealthcheckReport()", func() {
+		It("should write status, and why unhealthy checks are failing", func() {
+			buf := &bytes.Buffer{}
+			err := WriteHealthcheckReport(buf, ResourceItems{
+				healthcheck,
+				{
+					"name":           "origin-backup",
+					"address":        "backup.example.com",
+					"status":         "unhealthy",
+					"failure_reason": "TCP connection failed",
+				},
+				{
+					"name":      "origin-old",
+					"address":   "old.example.com",
+					"status":    "unknown",
+					"suspended": true,
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(Equal(`origin-primary  origin.example.com  status: healthy
+origin-backup   backup.example.com  status: unhealthy  TCP connection failed
+origin-old      old.example.com     status: suspended
+`))
+		})
+	})
+})
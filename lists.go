@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListsResource is an account's lists, such as IP lists and bulk redirect
+// lists, which are identified by their name.
+var ListsResource = ResourceType{
+	Name: "list",
+	Path: "/accounts/%s/rules/lists",
+	Key:  listKey,
+}
+
+func listKey(item ResourceItem) string {
+	name, _ := item["name"].(string)
+	return name
+}
+
+// listItemsResource describes the items of a list of a kind. It's only
+// used to compare items, which are replaced in bulk rather than one at a
+// time.
+func listItemsResource(kind string) ResourceType {
+	return ResourceType{
+		Name:     fmt.Sprintf("%s list item", kind),
+		Key:      func(item ResourceItem) string { return listItemKey(kind, item) },
+		ReadOnly: []string{"id", "created_on", "modified_on"},
+	}
+}
+
+// listItemKey returns the field of an item that identifies it in a list of
+// a kind, eg. the source URL of a redirect.
+func listItemKey(kind string, item ResourceItem) string {
+	switch kind {
+	case "redirect":
+		return resourceString(item, "redirect", "source_url")
+	case "hostname":
+		return resourceString(item, "hostname", "url_hostname")
+	case "asn":
+		if asn, ok := item["asn"].(float64); ok {
+			return strconv.FormatFloat(asn, 'f', -1, 64)
+		}
+		return ""
+	}
+
+	return resourceString(item, "ip")
+}
+
+// listCSVColumns are the columns of CSV files for each kind of list, which
+// are the same as the dashboard's. Redirect fields are nested within a
+// "redirect" object.
+var listCSVColumns = map[string][]string{
+	"ip": {"ip", "comment"},
+	"redirect": {
+		"source_url", "target_url", "status_code", "preserve_query_string",
+		"include_subdomains", "subpath_matching", "preserve_path_suffix",
+	},
+}
+
+func listCSVValue(column, value string) (interface{}, error) {
+	switch column {
+	case "status_code":
+		return strconv.ParseFloat(value, 64)
+	case "preserve_query_string", "include_subdomains", "subpath_matching", "preserve_path_suffix":
+		return strconv.ParseBool(value)
+	}
+
+	return value, nil
+}
+
+// LoadListItems reads the items of a list of a kind from a CSV file, if the
+// file has a .csv extension, or otherwise from a JSON file. Lines of CSV
+// files that start with # are ignored and empty cells are left out.
+func LoadListItems(file, kind string) (ResourceItems, error) {
+	if strings.ToLower(filepath.Ext(file)) != ".csv" {
+		return LoadResourceItems(file)
+	}
+
+	columns, ok := listCSVColumns[kind]
+	if !ok {
+		return nil, fmt.Errorf("Items of %s lists can't be read from CSV", kind)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	items := ResourceItems{}
+	for i, record := range records {
+		if len(record) > len(columns) {
+			return nil, fmt.Errorf("%s: record %d has %d columns, expected at most %d", file, i+1, len(record), len(columns))
+		}
+
+		fields := map[string]interface{}{}
+		for col, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			if fields[columns[col]], err = listCSVValue(columns[col], value); err != nil {
+				return nil, fmt.Errorf("%s: record %d has invalid %s %q", file, i+1, columns[col], value)
+			}
+		}
+
+		if kind == "redirect" {
+			items = append(items, ResourceItem{"redirect": fields})
+		} else {
+			items = append(items, ResourceItem(fields))
+		}
+	}
+
+	return items, nil
+}
+
+// SaveListItems writes the items of a list of a kind to a CSV or JSON
+// file, in the same way that LoadListItems reads them.
+func SaveListItems(items ResourceItems, kind, file string) error {
+	items = listItemsResource(kind).Strip(items)
+	if strings.ToLower(filepath.Ext(file)) != ".csv" {
+		return SaveResourceItems(items, file)
+	}
+
+	columns, ok := listCSVColumns[kind]
+	if !ok {
+		return fmt.Errorf("Items of %s lists can't be written to CSV", kind)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	for _, item := range items {
+		fields := map[string]interface{}(item)
+		if kind == "redirect" {
+			fields, _ = item["redirect"].(map[string]interface{})
+		}
+
+		record := []string{}
+		for _, column := range columns {
+			value := ""
+			if val, ok := fields[column]; ok {
+				value = fmt.Sprint(val)
+			}
+			record = append(record, value)
+		}
+
+		// Trailing empty cells are left out.
+		for len(record) > 0 && record[len(record)-1] == "" {
+			record = record[:len(record)-1]
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// listItemsResultInfo is the pagination of list items, which are paginated
+// by cursor rather than page number.
+type listItemsResultInfo struct {
+	ResultInfo struct {
+		Cursors struct {
+			After string `json:"after"`
+		} `json:"cursors"`
+	} `json:"result_info"`
+}
+
+// ListItems returns every item in a list, requesting each page with the
+// cursor from the previous page until there isn't one.
+func (c *CloudFlare) ListItems(account, listID string) (ResourceItems, error) {
+	items := ResourceItems{}
+	path := fmt.Sprintf("/accounts/%s/rules/lists/%s/items", account, listID)
+	cursor := ""
+
+	for {
+		pagePath := path
+		if cursor != "" {
+			pagePath = fmt.Sprintf("%s?cursor=%s", path, url.QueryEscape(cursor))
+		}
+
+		req, err := c.Query.NewRequest("GET", pagePath)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := c.requestBody(req)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := decodeResponse(body)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageItems ResourceItems
+		if err := json.Unmarshal(response.Result, &pageItems); err != nil {
+			return nil, err
+		}
+		items = append(items, pageItems...)
+
+		var info listItemsResultInfo
+		if err := json.Unmarshal(body, &info); err != nil {
+			return nil, err
+		}
+
+		cursor = info.ResultInfo.Cursors.After
+		if cursor == "" {
+			return items, nil
+		}
+	}
+}
+
+// List returns the list with a name, or nil if there isn't one.
+func (c *CloudFlare) List(account, name string) (ResourceItem, error) {
+	lists, err := c.ResourceItems(ListsResource, account)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range lists {
+		if listKey(list) == name {
+			return list, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ListItemsForUpdate summarises the changes to a list's items by key, and
+// has every expected item, because the items are replaced in bulk. Fields
+// that aren't expected keep their current values, in the same way that
+// they're compared.
+type ListItemsForUpdate struct {
+	Added   []string
+	Removed []string
+	Changed []string
+	Items   ResourceItems
+}
+
+func (l ListItemsForUpdate) Empty() bool {
+	return len(l.Added)+len(l.Removed)+len(l.Changed) == 0
+}
+
+func CompareListItemsForUpdate(kind string, current, expected ResourceItems) (ListItemsForUpdate, error) {
+	update := ListItemsForUpdate{Added: []string{}, Removed: []string{}, Changed: []string{}, Items: ResourceItems{}}
+
+	resource := listItemsResource(kind)
+	changes, err := CompareResourceItemsForUpdate(resource, current, expected)
+	if err != nil {
+		return update, err
+	}
+
+	for _, change := range changes {
+		switch change.Action() {
+		case "create":
+			update.Added = append(update.Added, change.Key)
+		case "delete":
+			update.Removed = append(update.Removed, change.Key)
+		case "update":
+			update.Changed = append(update.Changed, change.Key)
+		}
+	}
+
+	currentByKey := map[string]ResourceItem{}
+	for _, item := range resource.Strip(current) {
+		currentByKey[resource.Key(item)] = item
+	}
+
+	for _, item := range expected {
+		merged, ok := currentByKey[resource.Key(item)]
+		if !ok {
+			merged = ResourceItem{}
+		}
+		mergeResourceObject(merged, item)
+		update.Items = append(update.Items, merged)
+	}
+
+	return update, nil
+}
+
+// ReplaceListItems starts a bulk operation that replaces all of a list's
+// items, and returns the ID of the operation.
+func (c *CloudFlare) ReplaceListItems(account, listID string, items ResourceItems) (string, error) {
+	path := fmt.Sprintf("/accounts/%s/rules/lists/%s/items", account, listID)
+	result, err := c.sendResourceItem("PUT", path, items)
+	if err != nil {
+		return "", err
+	}
+
+	id, _ := result["operation_id"].(string)
+
+	return id, nil
+}
+
+// WaitForListOperation checks the status of a bulk operation every
+// interval until it has completed or failed, or until timeout has passed.
+func (c *CloudFlare) WaitForListOperation(account, operationID string, interval, timeout time.Duration) error {
+	path := fmt.Sprintf("/accounts/%s/rules/lists/bulk_operations/%s", account, operationID)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		req, err := c.Query.NewRequest("GET", path)
+		if err != nil {
+			return err
+		}
+
+		response, err := c.MakeRequest(req)
+		if err != nil {
+			return err
+		}
+
+		var operation struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := json.Unmarshal(response.Result, &operation); err != nil {
+			return err
+		}
+
+		switch operation.Status {
+		case "completed":
+			return nil
+		case "failed":
+			return fmt.Errorf("Bulk operation %s failed: %s", operationID, operation.Error)
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("Bulk operation %s is still %s after %s", operationID, operation.Status, timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// UpdateListItems logs the entries that are added, removed and changed,
+// followed by a summary, and then replaces all of the list's items unless
+// logOnly is true. Nothing is replaced if there aren't any changes.
+func (c *CloudFlare) UpdateListItems(account, name, listID string, update ListItemsForUpdate, interval, timeout time.Duration, logOnly bool) error {
+	for _, entry := range []struct {
+		action, preposition string
+		keys                []string
+	}{{"add", "to", update.Added}, {"remove", "from", update.Removed}, {"update", "in", update.Changed}} {
+		for _, key := range entry.keys {
+			c.log.Printf("%s %q %s list %q", resourceAction(entry.action, logOnly), key, entry.preposition, name)
+		}
+	}
+	c.log.Printf("List %q: %d added, %d removed, %d changed", name,
+		len(update.Added), len(update.Removed), len(update.Changed))
+
+	if logOnly || update.Empty() {
+		return nil
+	}
+
+	operationID, err := c.ReplaceListItems(account, listID, update.Items)
+	if err == nil {
+		err = c.WaitForListOperation(account, operationID, interval, timeout)
+	}
+
+	before := map[string]interface{}{"removed": update.Removed, "changed": update.Changed}
+	after := map[string]interface{}{"added": update.Added, "changed": update.Changed}
+
+	return c.audit(account, fmt.Sprintf("list %s", name), before, after, err)
+}
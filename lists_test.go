@@ -0,0 +1,259 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ = Describe("Lists", func() {
+	var (
+		server     *ghttp.Server
+		logbuf     *gbytes.Buffer
+		cloudFlare *CloudFlare
+		dir        string
+	)
+
+	respondWithItems := func(result string) http.HandlerFunc {
+		return ghttp.RespondWith(http.StatusOK, `{"errors": [], "messages": [], "success": true, "result": `+result+`}`)
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "cloudflare-configure")
+		Expect(err).To(BeNil())
+
+		server = ghttp.NewServer()
+		logbuf = gbytes.NewBuffer()
+		cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	Describe("LoadListItems()", func() {
+		It("should read IP list items from CSV, skipping comments and empty cells", func() {
+			file := filepath.Join(dir, "office.csv")
+			Expect(ioutil.WriteFile(file, []byte("# office ranges\n192.0.2.0/24,London\n198.51.100.1,\n"), 0644)).To(Succeed())
+
+			items, err := LoadListItems(file, "ip")
+
+			Expect(err).To(BeNil())
+			Expect(items).To(Equal(ResourceItems{
+				{"ip": "192.0.2.0/24", "comment": "London"},
+				{"ip": "198.51.100.1"},
+			}))
+		})
+
+		It("should read redirect list items from CSV with typed values", func() {
+			file := filepath.Join(dir, "redirects.csv")
+			Expect(ioutil.WriteFile(file, []byte("example.com/old,https://example.com/new,301,true\n"), 0644)).To(Succeed())
+
+			items, err := LoadListItems(file, "redirect")
+
+			Expect(err).To(BeNil())
+			Expect(items).To(Equal(ResourceItems{
+				{"redirect": map[string]interface{}{
+					"source_url":            "example.com/old",
+					"target_url":            "https://example.com/new",
+					"status_code":           float64(301),
+					"preserve_query_string": true,
+				}},
+			}))
+		})
+
+		It("should return an error for invalid values", func() {
+			file := filepath.Join(dir, "redirects.csv")
+			Expect(ioutil.WriteFile(file, []byte("example.com/old,https://example.com/new,moved\n"), 0644)).To(Succeed())
+
+			_, err := LoadListItems(file, "redirect")
+
+			Expect(err).To(MatchError(file + `: record 1 has invalid status_code "moved"`))
+		})
+
+		It("should read the same items that SaveListItems writes", func() {
+			file := filepath.Join(dir, "redirects.csv")
+			items := ResourceItems{
+				{"id": "2c0fc9fa937b11eaa1b71c4d701ab86e", "redirect": map[string]interface{}{
+					"source_url":         "example.com/blog",
+					"target_url":         "https://blog.example.com/",
+					"status_code":        float64(302),
+					"include_subdomains": true,
+				}},
+			}
+
+			Expect(SaveListItems(items, "redirect", file)).To(Succeed())
+			Expect(ioutil.ReadFile(file)).To(Equal([]byte("example.com/blog,https://blog.example.com/,302,,true\n")))
+
+			loaded, err := LoadListItems(file, "redirect")
+			Expect(err).To(BeNil())
+			Expect(loaded).To(Equal(ResourceItems{
+				{"redirect": map[string]interface{}{
+					"source_url":         "example.com/blog",
+					"target_url":         "https://blog.example.com/",
+					"status_code":        float64(302),
+					"include_subdomains": true,
+				}},
+			}))
+		})
+	})
+
+	Describe("ListItems()", func() {
+		It("should follow cursors to request every page", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/rules/lists/l1/items", ""),
+					ghttp.RespondWith(http.StatusOK, `{
+						"success": true, "errors": [], "messages": [],
+						"result": [{"id": "i1", "ip": "192.0.2.1"}],
+						"result_info": {"cursors": {"after": "yyy="}}
+					}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/rules/lists/l1/items", "cursor=yyy%3D"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"success": true, "errors": [], "messages": [],
+						"result": [{"id": "i2", "ip": "192.0.2.2"}],
+						"result_info": {"cursors": {"before": "xxx="}}
+					}`),
+				),
+			)
+
+			items, err := cloudFlare.ListItems("abc", "l1")
+
+			Expect(err).To(BeNil())
+			Expect(items).To(Equal(ResourceItems{
+				{"id": "i1", "ip": "192.0.2.1"},
+				{"id": "i2", "ip": "192.0.2.2"},
+			}))
+		})
+	})
+
+	Describe("UpdateListItems()", func() {
+		current := ResourceItems{
+			{"id": "i1", "ip": "192.0.2.1", "comment": "London"},
+			{"id": "i2", "ip": "192.0.2.2"},
+			{"id": "i4", "ip": "192.0.2.4", "comment": "Bristol", "created_on": "2014-10-17T14:16:15Z"},
+		}
+		expected := ResourceItems{
+			{"ip": "192.0.2.1", "comment": "Manchester"},
+			{"ip": "192.0.2.3"},
+			{"ip": "192.0.2.4"},
+		}
+
+		It("should summarise the entries that are added, removed and changed", func() {
+			update, err := CompareListItemsForUpdate("ip", current, expected)
+
+			Expect(err).To(BeNil())
+			Expect(update.Added).To(Equal([]string{"192.0.2.3"}))
+			Expect(update.Removed).To(Equal([]string{"192.0.2.2"}))
+			Expect(update.Changed).To(Equal([]string{"192.0.2.1"}))
+		})
+
+		It("should log the changes without making them when logOnly is true", func() {
+			update, err := CompareListItemsForUpdate("ip", current, expected)
+			Expect(err).To(BeNil())
+
+			Expect(cloudFlare.UpdateListItems("abc", "office", "l1", update, 0, time.Minute, true)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+
+			Expect(logbuf).To(gbytes.Say(`Would have added "192.0.2.3" to list "office"`))
+			Expect(logbuf).To(gbytes.Say(`Would have removed "192.0.2.2" from list "office"`))
+			Expect(logbuf).To(gbytes.Say(`Would have changed "192.0.2.1" in list "office"`))
+			Expect(logbuf).To(gbytes.Say(`List "office": 1 added, 1 removed, 1 changed`))
+		})
+
+		It("should replace every item in one bulk operation and wait for it", func() {
+			update, err := CompareListItemsForUpdate("ip", current, expected)
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/accounts/abc/rules/lists/l1/items"),
+					ghttp.VerifyJSON(`[
+						{"ip": "192.0.2.1", "comment": "Manchester"},
+						{"ip": "192.0.2.3"},
+						{"ip": "192.0.2.4", "comment": "Bristol"}
+					]`),
+					respondWithItems(`{"operation_id": "op1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/rules/lists/bulk_operations/op1"),
+					respondWithItems(`{"id": "op1", "status": "running"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/rules/lists/bulk_operations/op1"),
+					respondWithItems(`{"id": "op1", "status": "completed"}`),
+				),
+			)
+
+			Expect(cloudFlare.UpdateListItems("abc", "office", "l1", update, 0, time.Minute, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+		})
+
+		It("should return an error if the bulk operation fails", func() {
+			update, err := CompareListItemsForUpdate("ip", current, expected)
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/accounts/abc/rules/lists/l1/items"),
+					respondWithItems(`{"operation_id": "op1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/rules/lists/bulk_operations/op1"),
+					respondWithItems(`{"id": "op1", "status": "failed", "error": "invalid IP"}`),
+				),
+			)
+
+			err = cloudFlare.UpdateListItems("abc", "office", "l1", update, 0, time.Minute, false)
+
+			Expect(err).To(MatchError("Bulk operation op1 failed: invalid IP"))
+		})
+
+		It("should return an error with the operation ID if it doesn't complete before the timeout", func() {
+			update, err := CompareListItemsForUpdate("ip", current, expected)
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/accounts/abc/rules/lists/l1/items"),
+					respondWithItems(`{"operation_id": "op1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/rules/lists/bulk_operations/op1"),
+					respondWithItems(`{"id": "op1", "status": "pending"}`),
+				),
+			)
+
+			err = cloudFlare.UpdateListItems("abc", "office", "l1", update, 0, 0, false)
+
+			Expect(err).To(MatchError("Bulk operation op1 is still pending after 0s"))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+
+		It("should not replace items if nothing has changed", func() {
+			update, err := CompareListItemsForUpdate("ip", current, ResourceItems{
+				{"ip": "192.0.2.1", "comment": "London"},
+				{"ip": "192.0.2.2"},
+				{"ip": "192.0.2.4", "comment": "Bristol"},
+			})
+			Expect(err).To(BeNil())
+
+			Expect(cloudFlare.UpdateListItems("abc", "office", "l1", update, 0, time.Minute, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+		})
+	})
+})
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// LoadBalancerMonitorsResource manages an account's health monitors, which
+// are identified by their description.
+var LoadBalancerMonitorsResource = ResourceType{
+	Name:         "monitor",
+	Path:         "/accounts/%s/load_balancers/monitors",
+	Key:          loadBalancerMonitorKey,
+	ReadOnly:     []string{"id", "created_on", "modified_on"},
+	UpdateMethod: "PUT",
+}
+
+// LoadBalancerPoolsResource manages an account's origin pools, which are
+// identified by their name.
+var LoadBalancerPoolsResource = ResourceType{
+	Name:         "pool",
+	Path:         "/accounts/%s/load_balancers/pools",
+	Key:          loadBalancerNameKey,
+	ReadOnly:     []string{"id", "created_on", "modified_on", "healthy"},
+	UpdateMethod: "PUT",
+}
+
+// LoadBalancersResource manages a zone's load balancers, which are
+// identified by their hostname.
+var LoadBalancersResource = ResourceType{
+	Name:         "load balancer",
+	Path:         "/zones/%s/load_balancers",
+	Key:          loadBalancerNameKey,
+	ReadOnly:     []string{"id", "created_on", "modified_on"},
+	UpdateMethod: "PUT",
+}
+
+func loadBalancerMonitorKey(item ResourceItem) string {
+	description, _ := item["description"].(string)
+	return description
+}
+
+func loadBalancerNameKey(item ResourceItem) string {
+	name, _ := item["name"].(string)
+	return name
+}
+
+// LoadBalancerConfig is the monitors, pools and load balancers that are
+// managed together. Pools refer to monitors, and load balancers refer to
+// pools, by name instead of ID so that config can be used for more than
+// one account.
+type LoadBalancerConfig struct {
+	Monitors      ResourceItems `json:"monitors"`
+	Pools         ResourceItems `json:"pools"`
+	LoadBalancers ResourceItems `json:"load_balancers"`
+}
+
+type LoadBalancerConfigForUpdate struct {
+	Monitors      ResourceItemsForUpdate
+	Pools         ResourceItemsForUpdate
+	LoadBalancers ResourceItemsForUpdate
+}
+
+// These are the fields of a load balancer that refer to pools: a pool, a
+// list of pools, and objects of lists of pools keyed by region, PoP or
+// country.
+var (
+	loadBalancerPoolField      = "fallback_pool"
+	loadBalancerPoolListField  = "default_pools"
+	loadBalancerPoolListFields = []string{"region_pools", "pop_pools", "country_pools"}
+)
+
+// mapPoolRefs returns a copy of a pool with its monitor reference mapped.
+func mapPoolRefs(pool ResourceItem, mapping func(string) (string, error)) (ResourceItem, error) {
+	mapped := ResourceItem(copyResourceObject(pool))
+
+	if monitor, ok := mapped["monitor"].(string); ok && monitor != "" {
+		ref, err := mapping(monitor)
+		if err != nil {
+			return nil, err
+		}
+		mapped["monitor"] = ref
+	}
+
+	return mapped, nil
+}
+
+// mapLoadBalancerRefs returns a copy of a load balancer with its pool
+// references mapped.
+func mapLoadBalancerRefs(lb ResourceItem, mapping func(string) (string, error)) (ResourceItem, error) {
+	mapped := ResourceItem(copyResourceObject(lb))
+
+	mapList := func(val interface{}) (interface{}, error) {
+		list, ok := val.([]interface{})
+		if !ok {
+			return val, nil
+		}
+
+		refs := []interface{}{}
+		for _, item := range list {
+			if pool, ok := item.(string); ok {
+				ref, err := mapping(pool)
+				if err != nil {
+					return nil, err
+				}
+				item = ref
+			}
+			refs = append(refs, item)
+		}
+
+		return refs, nil
+	}
+
+	var err error
+	if pool, ok := mapped[loadBalancerPoolField].(string); ok && pool != "" {
+		if mapped[loadBalancerPoolField], err = mapping(pool); err != nil {
+			return nil, err
+		}
+	}
+	if list, ok := mapped[loadBalancerPoolListField]; ok {
+		if mapped[loadBalancerPoolListField], err = mapList(list); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range loadBalancerPoolListFields {
+		pools, ok := mapped[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, list := range pools {
+			if pools[key], err = mapList(list); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return mapped, nil
+}
+
+// LoadBalancerConfig returns the account's monitors and pools, and the
+// zone's load balancers, with references by name.
+func (c *CloudFlare) LoadBalancerConfig(account, zone string) (LoadBalancerConfig, error) {
+	var config LoadBalancerConfig
+
+	monitors, err := c.ResourceItems(LoadBalancerMonitorsResource, account)
+	if err != nil {
+		return config, err
+	}
+	pools, err := c.ResourceItems(LoadBalancerPoolsResource, account)
+	if err != nil {
+		return config, err
+	}
+	lbs, err := c.ResourceItems(LoadBalancersResource, zone)
+	if err != nil {
+		return config, err
+	}
+
+	monitorRefs := newResourceRefs(LoadBalancerMonitorsResource.Name, monitors, loadBalancerMonitorKey)
+	poolRefs := newResourceRefs(LoadBalancerPoolsResource.Name, pools, loadBalancerNameKey)
+
+	config = LoadBalancerConfig{Monitors: monitors, Pools: ResourceItems{}, LoadBalancers: ResourceItems{}}
+	for _, pool := range pools {
+		mapped, _ := mapPoolRefs(pool, monitorRefs.toName)
+		config.Pools = append(config.Pools, mapped)
+	}
+	for _, lb := range lbs {
+		mapped, _ := mapLoadBalancerRefs(lb, poolRefs.toName)
+		config.LoadBalancers = append(config.LoadBalancers, mapped)
+	}
+
+	return config, nil
+}
+
+// Strip returns a copy of the config without read-only fields.
+func (l LoadBalancerConfig) Strip() LoadBalancerConfig {
+	return LoadBalancerConfig{
+		Monitors:      LoadBalancerMonitorsResource.Strip(l.Monitors),
+		Pools:         LoadBalancerPoolsResource.Strip(l.Pools),
+		LoadBalancers: LoadBalancersResource.Strip(l.LoadBalancers),
+	}
+}
+
+// CompareLoadBalancerConfigForUpdate returns the changes to each type of
+// resource. Expected pools and load balancers may only refer to expected
+// monitors and pools, because any others would be deleted.
+func CompareLoadBalancerConfigForUpdate(current, expected LoadBalancerConfig) (LoadBalancerConfigForUpdate, error) {
+	var update LoadBalancerConfigForUpdate
+
+	monitorNames := newResourceRefs(LoadBalancerMonitorsResource.Name, expected.Monitors, loadBalancerMonitorKey)
+	for _, pool := range expected.Pools {
+		if _, err := mapPoolRefs(pool, monitorNames.checkName); err != nil {
+			return update, err
+		}
+	}
+
+	poolNames := newResourceRefs(LoadBalancerPoolsResource.Name, expected.Pools, loadBalancerNameKey)
+	for _, lb := range expected.LoadBalancers {
+		if _, err := mapLoadBalancerRefs(lb, poolNames.checkName); err != nil {
+			return update, err
+		}
+	}
+
+	var err error
+	if update.Monitors, err = CompareResourceItemsForUpdate(LoadBalancerMonitorsResource, current.Monitors, expected.Monitors); err != nil {
+		return update, err
+	}
+	if update.Pools, err = CompareResourceItemsForUpdate(LoadBalancerPoolsResource, current.Pools, expected.Pools); err != nil {
+		return update, err
+	}
+	if update.LoadBalancers, err = CompareResourceItemsForUpdate(LoadBalancersResource, current.LoadBalancers, expected.LoadBalancers); err != nil {
+		return update, err
+	}
+
+	return update, nil
+}
+
+// splitResourceDeletes separates deletes from creates and updates.
+func splitResourceDeletes(update ResourceItemsForUpdate) (ResourceItemsForUpdate, ResourceItemsForUpdate) {
+	changes, deletes := ResourceItemsForUpdate{}, ResourceItemsForUpdate{}
+	for _, change := range update {
+		if change.Action() == "delete" {
+			deletes = append(deletes, change)
+		} else {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, deletes
+}
+
+// SkipLoadBalancerAccountDeletes returns the changes without the deletion of
+// monitors and pools that aren't in the config, logging each one that's
+// left. They belong to the account, so they may be used by other zones'
+// load balancers.
+func (c *CloudFlare) SkipLoadBalancerAccountDeletes(update LoadBalancerConfigForUpdate) LoadBalancerConfigForUpdate {
+	var deletes ResourceItemsForUpdate
+	update.Monitors, deletes = splitResourceDeletes(update.Monitors)
+	for _, change := range deletes {
+		c.log.Printf("Not deleting %s %q, which isn't in config, without --prune", LoadBalancerMonitorsResource.Name, change.Key)
+	}
+
+	update.Pools, deletes = splitResourceDeletes(update.Pools)
+	for _, change := range deletes {
+		c.log.Printf("Not deleting %s %q, which isn't in config, without --prune", LoadBalancerPoolsResource.Name, change.Key)
+	}
+
+	return update
+}
+
+// UpdateLoadBalancerConfig makes changes in dependency order: monitors,
+// pools and then load balancers are created and updated, before load
+// balancers, pools and then monitors are deleted. The IDs that references
+// resolve to are looked up after each type of resource has been changed,
+// so that they include any that were just created.
+func (c *CloudFlare) UpdateLoadBalancerConfig(account, zone string, update LoadBalancerConfigForUpdate, logOnly bool) error {
+	monitorChanges, monitorDeletes := splitResourceDeletes(update.Monitors)
+	poolChanges, poolDeletes := splitResourceDeletes(update.Pools)
+
+	if err := c.UpdateResource(LoadBalancerMonitorsResource, account, monitorChanges, logOnly); err != nil {
+		return err
+	}
+
+	pools := LoadBalancerPoolsResource
+	if !logOnly {
+		monitors, err := c.ResourceItems(LoadBalancerMonitorsResource, account)
+		if err != nil {
+			return err
+		}
+		monitorRefs := newResourceRefs(LoadBalancerMonitorsResource.Name, monitors, loadBalancerMonitorKey)
+		pools.Resolve = func(item ResourceItem) (ResourceItem, error) {
+			return mapPoolRefs(item, monitorRefs.toID)
+		}
+	}
+	if err := c.UpdateResource(pools, account, poolChanges, logOnly); err != nil {
+		return err
+	}
+
+	lbs := LoadBalancersResource
+	if !logOnly {
+		current, err := c.ResourceItems(LoadBalancerPoolsResource, account)
+		if err != nil {
+			return err
+		}
+		poolRefs := newResourceRefs(LoadBalancerPoolsResource.Name, current, loadBalancerNameKey)
+		lbs.Resolve = func(item ResourceItem) (ResourceItem, error) {
+			return mapLoadBalancerRefs(item, poolRefs.toID)
+		}
+	}
+	if err := c.UpdateResource(lbs, zone, update.LoadBalancers, logOnly); err != nil {
+		return err
+	}
+
+	if err := c.UpdateResource(LoadBalancerPoolsResource, account, poolDeletes, logOnly); err != nil {
+		return err
+	}
+
+	return c.UpdateResource(LoadBalancerMonitorsResource, account, monitorDeletes, logOnly)
+}
+
+func LoadLoadBalancerConfig(file string) (LoadBalancerConfig, error) {
+	var config LoadBalancerConfig
+
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+
+	err = json.Unmarshal(bs, &config)
+
+	return config, err
+}
+
+func SaveLoadBalancerConfig(config LoadBalancerConfig, file string) error {
+	bs, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(file, bs, 0644)
+	return err
+}
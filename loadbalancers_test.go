@@ -0,0 +1,234 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("LoadBalancers", func() {
+	var (
+		server     *ghttp.Server
+		logbuf     *gbytes.Buffer
+		cloudFlare *CloudFlare
+	)
+
+	respondWithItems := func(result string) http.HandlerFunc {
+		return ghttp.RespondWith(http.StatusOK, `{"errors": [], "messages": [], "success": true, "result": `+result+`}`)
+	}
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		logbuf = gbytes.NewBuffer()
+		cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("LoadBalancerConfig()", func() {
+		It("should replace references to IDs with names", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/load_balancers/monitors"),
+					respondWithItems(`[{"id": "m1", "description": "HTTPS check"}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/load_balancers/pools"),
+					respondWithItems(`[{"id": "p1", "name": "primary", "monitor": "m1"}, {"id": "p2", "name": "backup"}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/zones/123/load_balancers"),
+					respondWithItems(`[{
+						"id": "l1",
+						"name": "www.example.com",
+						"default_pools": ["p1", "p2"],
+						"fallback_pool": "p2",
+						"region_pools": {"WEU": ["p2"]}
+					}]`),
+				),
+			)
+
+			config, err := cloudFlare.LoadBalancerConfig("abc", "123")
+
+			Expect(err).To(BeNil())
+			Expect(config.Strip()).To(Equal(LoadBalancerConfig{
+				Monitors: ResourceItems{
+					{"description": "HTTPS check"},
+				},
+				Pools: ResourceItems{
+					{"name": "primary", "monitor": "HTTPS check"},
+					{"name": "backup"},
+				},
+				LoadBalancers: ResourceItems{
+					{
+						"name":          "www.example.com",
+						"default_pools": []interface{}{"primary", "backup"},
+						"fallback_pool": "backup",
+						"region_pools":  map[string]interface{}{"WEU": []interface{}{"backup"}},
+					},
+				},
+			}))
+		})
+	})
+
+	Describe("CompareLoadBalancerConfigForUpdate()", func() {
+		It("should return an error for references to pools that aren't expected", func() {
+			_, err := CompareLoadBalancerConfigForUpdate(LoadBalancerConfig{}, LoadBalancerConfig{
+				Pools: ResourceItems{{"name": "primary"}},
+				LoadBalancers: ResourceItems{
+					{"name": "www.example.com", "default_pools": []interface{}{"primary", "secondary"}},
+				},
+			})
+
+			Expect(err).To(MatchError(`Reference to unknown pool "secondary"`))
+		})
+
+		It("should return an error for references to monitors that aren't expected", func() {
+			_, err := CompareLoadBalancerConfigForUpdate(LoadBalancerConfig{}, LoadBalancerConfig{
+				Pools: ResourceItems{{"name": "primary", "monitor": "HTTPS check"}},
+			})
+
+			Expect(err).To(MatchError(`Reference to unknown monitor "HTTPS check"`))
+		})
+	})
+
+	Describe("UpdateLoadBalancerConfig()", func() {
+		current := LoadBalancerConfig{
+			Monitors: ResourceItems{{"id": "m0", "description": "Old check"}},
+			Pools:    ResourceItems{{"id": "p0", "name": "old", "monitor": "Old check"}},
+		}
+		expected := LoadBalancerConfig{
+			Monitors:      ResourceItems{{"description": "HTTPS check"}},
+			Pools:         ResourceItems{{"name": "primary", "monitor": "HTTPS check"}},
+			LoadBalancers: ResourceItems{{"name": "www.example.com", "default_pools": []interface{}{"primary"}}},
+		}
+
+		It("should create and delete in dependency order, resolving names to IDs", func() {
+			update, err := CompareLoadBalancerConfigForUpdate(current, expected)
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/accounts/abc/load_balancers/monitors"),
+					ghttp.VerifyJSON(`{"description": "HTTPS check"}`),
+					respondWithItems(`{"id": "m1", "description": "HTTPS check"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/load_balancers/monitors"),
+					respondWithItems(`[{"id": "m0", "description": "Old check"}, {"id": "m1", "description": "HTTPS check"}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/accounts/abc/load_balancers/pools"),
+					ghttp.VerifyJSON(`{"name": "primary", "monitor": "m1"}`),
+					respondWithItems(`{"id": "p1", "name": "primary", "monitor": "m1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/load_balancers/pools"),
+					respondWithItems(`[{"id": "p0", "name": "old"}, {"id": "p1", "name": "primary"}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/load_balancers"),
+					ghttp.VerifyJSON(`{"name": "www.example.com", "default_pools": ["p1"]}`),
+					respondWithItems(`{"id": "l1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/accounts/abc/load_balancers/pools/p0"),
+					respondWithItems(`{"id": "p0"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/accounts/abc/load_balancers/monitors/m0"),
+					respondWithItems(`{"id": "m0"}`),
+				),
+			)
+
+			Expect(cloudFlare.UpdateLoadBalancerConfig("abc", "123", update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(7))
+		})
+
+		It("should record changes to the account's monitors and pools against the zone", func() {
+			tempDir, err := ioutil.TempDir("", "cloudflare-configure")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(tempDir)
+
+			cloudFlare.Journal = &AuditJournal{
+				File:     filepath.Join(tempDir, "audit.jsonl"),
+				ZoneID:   "123",
+				ZoneName: "example.com",
+			}
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/load_balancers/monitors"),
+					respondWithItems(`[]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/load_balancers/pools"),
+					respondWithItems(`[{"id": "p0", "name": "old"}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/zones/123/load_balancers/l1"),
+					respondWithItems(`{"id": "l1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/accounts/abc/load_balancers/pools/p0"),
+					respondWithItems(`{"id": "p0"}`),
+				),
+			)
+
+			Expect(cloudFlare.UpdateLoadBalancerConfig("abc", "123", LoadBalancerConfigForUpdate{
+				Pools: ResourceItemsForUpdate{{Key: "old", Current: ResourceItem{"id": "p0", "name": "old"}}},
+				LoadBalancers: ResourceItemsForUpdate{{
+					Key:      "www.example.com",
+					Current:  ResourceItem{"id": "l1", "name": "www.example.com", "proxied": false},
+					Expected: ResourceItem{"name": "www.example.com", "proxied": true},
+				}},
+			}, false)).To(BeNil())
+
+			entries, err := LoadAuditEntries(cloudFlare.Journal.File, AuditFilter{Zone: "123"})
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Key).To(Equal(`load balancer www.example.com`))
+			Expect(entries[0].AccountID).To(BeEmpty())
+			Expect(entries[1].Key).To(Equal(`pool old`))
+			Expect(entries[1].AccountID).To(Equal("abc"))
+		})
+
+		It("should leave monitors and pools that aren't in config when deletes are skipped", func() {
+			update, err := CompareLoadBalancerConfigForUpdate(current, expected)
+			Expect(err).To(BeNil())
+
+			update = cloudFlare.SkipLoadBalancerAccountDeletes(update)
+			Expect(logbuf).To(gbytes.Say(`Not deleting monitor "Old check", which isn't in config, without --prune`))
+			Expect(logbuf).To(gbytes.Say(`Not deleting pool "old", which isn't in config, without --prune`))
+
+			Expect(cloudFlare.UpdateLoadBalancerConfig("abc", "123", update, true)).To(BeNil())
+			Expect(logbuf).To(gbytes.Say(`Would have created load balancer "www.example.com"`))
+			Expect(logbuf).ToNot(gbytes.Say(`Would have deleted`))
+		})
+
+		It("should log changes with names without making them when logOnly is true", func() {
+			update, err := CompareLoadBalancerConfigForUpdate(current, expected)
+			Expect(err).To(BeNil())
+
+			Expect(cloudFlare.UpdateLoadBalancerConfig("abc", "123", update, true)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+
+			Expect(logbuf).To(gbytes.Say(`Would have created monitor "HTTPS check"`))
+			Expect(logbuf).To(gbytes.Say(`Would have created pool "primary": {"monitor":"HTTPS check","name":"primary"}`))
+			Expect(logbuf).To(gbytes.Say(`Would have created load balancer "www.example.com": {"default_pools":\["primary"\],"name":"www.example.com"}`))
+			Expect(logbuf).To(gbytes.Say(`Would have deleted pool "old"`))
+			Expect(logbuf).To(gbytes.Say(`Would have deleted monitor "Old check"`))
+		})
+	})
+})
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const zoneLockPollInterval = 250 * time.Millisecond
+
+type ZoneLockHolder struct {
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	User     string    `json:"user"`
+	Acquired time.Time `json:"acquired"`
+}
+
+type ZoneLocked struct {
+	Zone   string
+	File   string
+	Holder ZoneLockHolder
+}
+
+func (z ZoneLocked) Error() string {
+	return fmt.Sprintf("Zone %s is locked by %s@%s (pid %d) since %s, lock file: %s",
+		z.Zone, z.Holder.User, z.Holder.Host, z.Holder.PID,
+		z.Holder.Acquired.Format(time.RFC3339), z.File)
+}
+
+// ZoneLock is an advisory lock, held by creating a file named after the
+// zone, that prevents more than one process from applying changes to a zone
+// at the same time.
+type ZoneLock struct {
+	File   string
+	Holder ZoneLockHolder
+}
+
+// AcquireZoneLock takes the lock for a zone in dir, waiting for up to wait
+// for another holder to release it. A lock is considered stale, and is
+// removed, if it is older than stale or was taken by a process on this host
+// that is no longer running. A stale of zero disables the age check.
+func AcquireZoneLock(dir, zone string, wait, stale time.Duration) (*ZoneLock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	lock := &ZoneLock{
+		File: filepath.Join(dir, fmt.Sprintf("%s.lock", zone)),
+		Holder: ZoneLockHolder{
+			PID:      os.Getpid(),
+			Host:     host,
+			User:     os.Getenv("USER"),
+			Acquired: time.Now().UTC(),
+		},
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err := lock.create()
+		if err == nil {
+			return lock, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		holder, err := readZoneLock(lock.File)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if holder.stale(lock.File, host, stale) {
+			if err := os.Remove(lock.File); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			continue
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, ZoneLocked{Zone: zone, File: lock.File, Holder: holder}
+		}
+		time.Sleep(zoneLockPollInterval)
+	}
+}
+
+// Release removes the lock. It is safe to call on a nil lock.
+func (l *ZoneLock) Release() error {
+	if l == nil {
+		return nil
+	}
+
+	return os.Remove(l.File)
+}
+
+func (l *ZoneLock) create() error {
+	file, err := os.OpenFile(l.File, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bs, err := json.Marshal(l.Holder)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(bs)
+
+	return err
+}
+
+// readZoneLock returns the holder of a lock file. A lock that can't be
+// parsed, because it's still being written, returns an empty holder.
+func readZoneLock(file string) (ZoneLockHolder, error) {
+	var holder ZoneLockHolder
+
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return holder, err
+	}
+
+	json.Unmarshal(bs, &holder)
+
+	return holder, nil
+}
+
+func (h ZoneLockHolder) stale(file, host string, stale time.Duration) bool {
+	if stale > 0 {
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) > stale {
+			return true
+		}
+	}
+
+	if h.PID > 0 && h.Host == host {
+		return !processRunning(h.PID)
+	}
+
+	return false
+}
+
+// processRunning sends signal 0 to a process, which checks that it exists
+// without affecting it. A permission error means that it exists but belongs
+// to another user.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	if err == nil {
+		return true
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		return sysErr.Err == syscall.EPERM
+	}
+
+	return false
+}
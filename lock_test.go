@@ -0,0 +1,107 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ = Describe("ZoneLock", func() {
+	var (
+		tempDir  string
+		lockFile string
+	)
+
+	const zoneID = "123"
+
+	writeLock := func(holder ZoneLockHolder) {
+		bs, err := json.Marshal(holder)
+		Expect(err).To(BeNil())
+		Expect(ioutil.WriteFile(lockFile, bs, 0644)).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "cloudflare-configure")
+		Expect(err).To(BeNil())
+
+		lockFile = filepath.Join(tempDir, "123.lock")
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		Expect(err).To(BeNil())
+	})
+
+	It("should create a lock file containing the holder", func() {
+		lock, err := AcquireZoneLock(tempDir, zoneID, 0, time.Hour)
+		Expect(err).To(BeNil())
+		Expect(lock.File).To(Equal(lockFile))
+
+		bs, err := ioutil.ReadFile(lockFile)
+		Expect(err).To(BeNil())
+
+		var holder ZoneLockHolder
+		Expect(json.Unmarshal(bs, &holder)).To(BeNil())
+		Expect(holder.PID).To(Equal(os.Getpid()))
+
+		Expect(lock.Release()).To(BeNil())
+		_, err = os.Stat(lockFile)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should fail fast with the holder when the zone is already locked", func() {
+		lock, err := AcquireZoneLock(tempDir, zoneID, 0, time.Hour)
+		Expect(err).To(BeNil())
+		defer lock.Release()
+
+		second, err := AcquireZoneLock(tempDir, zoneID, 0, time.Hour)
+		Expect(second).To(BeNil())
+		Expect(err).To(BeAssignableToTypeOf(ZoneLocked{}))
+		Expect(err.(ZoneLocked).Holder.PID).To(Equal(os.Getpid()))
+	})
+
+	It("should wait for the holder to release the lock", func() {
+		lock, err := AcquireZoneLock(tempDir, zoneID, 0, time.Hour)
+		Expect(err).To(BeNil())
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			lock.Release()
+		}()
+
+		second, err := AcquireZoneLock(tempDir, zoneID, 5*time.Second, time.Hour)
+		Expect(err).To(BeNil())
+		Expect(second.Release()).To(BeNil())
+	})
+
+	It("should replace a lock held by a process on this host that has exited", func() {
+		host, _ := os.Hostname()
+		writeLock(ZoneLockHolder{PID: 99999999, Host: host, Acquired: time.Now()})
+
+		lock, err := AcquireZoneLock(tempDir, zoneID, 0, time.Hour)
+		Expect(err).To(BeNil())
+		Expect(lock.Release()).To(BeNil())
+	})
+
+	It("should replace a lock older than the stale age", func() {
+		writeLock(ZoneLockHolder{PID: 1, Host: "elsewhere.example.com"})
+
+		_, err := AcquireZoneLock(tempDir, zoneID, 0, time.Hour)
+		Expect(err).To(BeAssignableToTypeOf(ZoneLocked{}))
+
+		old := time.Now().Add(-2 * time.Hour)
+		Expect(os.Chtimes(lockFile, old, old)).To(BeNil())
+
+		lock, err := AcquireZoneLock(tempDir, zoneID, 0, time.Hour)
+		Expect(err).To(BeNil())
+		Expect(lock.Release()).To(BeNil())
+	})
+})
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// LogpushJobsResource manages the jobs that push a zone's logs to a
+// destination. Jobs are identified by their name, or by their dataset and
+// destination if they don't have one.
+var LogpushJobsResource = ResourceType{
+	Name:         "logpush job",
+	Path:         "/zones/%s/logpush/jobs",
+	Key:          logpushJobKey,
+	ReadOnly:     []string{"id", "last_complete", "last_error", "error_message"},
+	CreateOnly:   []string{"dataset"},
+	UpdateMethod: "PUT",
+	Describe:     describeLogpushJobChange,
+}
+
+func logpushJobKey(item ResourceItem) string {
+	if name, _ := item["name"].(string); name != "" {
+		return name
+	}
+
+	dataset, _ := item["dataset"].(string)
+	destination, _ := item["destination_conf"].(string)
+
+	return fmt.Sprintf("%s %s", dataset, destination)
+}
+
+// logpushChallengeSchemes are the types of destination that CloudFlare
+// must prove that the zone owns before logs are pushed to them.
+var logpushChallengeSchemes = []string{"s3", "gs", "azure", "sumo"}
+
+func logpushDestinationNeedsChallenge(destination string) bool {
+	for _, scheme := range logpushChallengeSchemes {
+		if strings.HasPrefix(destination, scheme+"://") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// logpushJobFields returns the fields that a job pushes, which are either
+// in the logpull_options query string or, for newer jobs, output_options.
+func logpushJobFields(item ResourceItem) []string {
+	if options, ok := item["output_options"].(map[string]interface{}); ok {
+		if names, ok := options["field_names"].([]interface{}); ok {
+			fields := []string{}
+			for _, name := range names {
+				if field, ok := name.(string); ok {
+					fields = append(fields, field)
+				}
+			}
+			return fields
+		}
+	}
+
+	if options, ok := item["logpull_options"].(string); ok {
+		query, _ := url.ParseQuery(options)
+		if fields := query.Get("fields"); fields != "" {
+			return strings.Split(fields, ",")
+		}
+	}
+
+	return nil
+}
+
+// withoutLogpushJobFields returns a copy of a job without its fields.
+func withoutLogpushJobFields(item ResourceItem) ResourceItem {
+	copied := ResourceItem(copyResourceObject(item))
+
+	if options, ok := copied["output_options"].(map[string]interface{}); ok {
+		delete(options, "field_names")
+	}
+
+	if options, ok := copied["logpull_options"].(string); ok {
+		query, _ := url.ParseQuery(options)
+		query.Del("fields")
+		copied["logpull_options"] = query.Encode()
+	}
+
+	return copied
+}
+
+// describeLogpushJobChange lists the fields that are added to and removed
+// from a job, instead of the whole field lists, followed by any other
+// changes.
+func describeLogpushJobChange(from, to ResourceItem) string {
+	fromFields, toFields := logpushJobFields(from), logpushJobFields(to)
+	added, removed := diffStrings(fromFields, toFields), diffStrings(toFields, fromFields)
+
+	from, to = withoutLogpushJobFields(from), withoutLogpushJobFields(to)
+	for key := range from {
+		if resourceValueMatches(from[key], to[key]) {
+			delete(from, key)
+			delete(to, key)
+		}
+	}
+
+	parts := []string{}
+	if len(added) > 0 {
+		parts = append(parts, fmt.Sprintf("adding fields %s", strings.Join(added, ", ")))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, fmt.Sprintf("removing fields %s", strings.Join(removed, ", ")))
+	}
+	if len(added) == 0 && len(removed) == 0 && !reflect.DeepEqual(fromFields, toFields) {
+		parts = append(parts, fmt.Sprintf("reordering fields to %s", strings.Join(toFields, ", ")))
+	}
+	if len(from) > 0 || len(to) > 0 {
+		parts = append(parts, fmt.Sprintf("from %s to %s", jsonString(from), jsonString(to)))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// diffStrings returns the strings in b that aren't in a.
+func diffStrings(a, b []string) []string {
+	inA := map[string]bool{}
+	for _, str := range a {
+		inA[str] = true
+	}
+
+	diff := []string{}
+	for _, str := range b {
+		if !inA[str] {
+			diff = append(diff, str)
+		}
+	}
+
+	return diff
+}
+
+// RequestLogpushOwnershipChallenge asks CloudFlare to write a file that
+// contains a challenge token to a destination. The name of the file is
+// returned.
+func (c *CloudFlare) RequestLogpushOwnershipChallenge(zone, destination string) (string, error) {
+	result, err := c.sendResourceItem("POST", fmt.Sprintf("/zones/%s/logpush/ownership", zone),
+		map[string]interface{}{"destination_conf": destination})
+	if err != nil {
+		return "", err
+	}
+
+	filename, _ := result["filename"].(string)
+
+	return filename, nil
+}
+
+func (c *CloudFlare) ValidateLogpushOwnershipChallenge(zone, destination, challenge string) (bool, error) {
+	result, err := c.sendResourceItem("POST", fmt.Sprintf("/zones/%s/logpush/ownership/validate", zone),
+		map[string]interface{}{"destination_conf": destination, "ownership_challenge": challenge})
+	if err != nil {
+		return false, err
+	}
+
+	valid, _ := result["valid"].(bool)
+
+	return valid, nil
+}
+
+// logpushChallengeDestinations returns the destinations of jobs that will
+// be created, or moved to a new destination, that need a challenge.
+func logpushChallengeDestinations(update ResourceItemsForUpdate) []string {
+	destinations := []string{}
+	seen := map[string]bool{}
+	for _, change := range update {
+		if change.Action() == "delete" {
+			continue
+		}
+
+		destination, ok := change.Expected["destination_conf"].(string)
+		if !ok || (change.Current != nil && change.Current["destination_conf"] == destination) {
+			continue
+		}
+
+		if logpushDestinationNeedsChallenge(destination) && !seen[destination] {
+			destinations = append(destinations, destination)
+			seen[destination] = true
+		}
+	}
+
+	return destinations
+}
+
+// UpdateLogpushJobs makes changes to a zone's jobs. One of challenges must
+// be valid for each new destination that needs one. If none are then
+// CloudFlare is asked to write a challenge to the destination and no
+// changes are made, so that its token can be passed in when trying again.
+func (c *CloudFlare) UpdateLogpushJobs(zone string, update ResourceItemsForUpdate, challenges []string, logOnly bool) error {
+	tokens := map[string]string{}
+	missing := 0
+	for _, destination := range logpushChallengeDestinations(update) {
+		if logOnly {
+			c.log.Printf("Destination %q will need an ownership challenge", destination)
+			continue
+		}
+
+		for _, challenge := range challenges {
+			valid, err := c.ValidateLogpushOwnershipChallenge(zone, destination, challenge)
+			if err != nil {
+				return err
+			}
+			if valid {
+				tokens[destination] = challenge
+				break
+			}
+		}
+		if tokens[destination] != "" {
+			continue
+		}
+
+		filename, err := c.RequestLogpushOwnershipChallenge(zone, destination)
+		if err != nil {
+			return err
+		}
+		c.log.Printf("Wrote ownership challenge for destination %q to %q", destination, filename)
+		missing++
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("Ownership challenge needed for %d destinations, pass the token from each file with --challenge", missing)
+	}
+
+	jobs := LogpushJobsResource
+	jobs.Resolve = func(item ResourceItem) (ResourceItem, error) {
+		destination, _ := item["destination_conf"].(string)
+		if token, ok := tokens[destination]; ok {
+			item = ResourceItem(copyResourceObject(item))
+			item["ownership_challenge"] = token
+		}
+
+		return item, nil
+	}
+
+	return c.UpdateResource(jobs, zone, update, logOnly)
+}
@@ -0,0 +1,172 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"log"
+	"net/http"
+)
+
+var _ = Describe("Logpush", func() {
+	var (
+		server     *ghttp.Server
+		logbuf     *gbytes.Buffer
+		cloudFlare *CloudFlare
+	)
+
+	respondWithItem := func(result string) http.HandlerFunc {
+		return ghttp.RespondWith(http.StatusOK, `{"errors": [], "messages": [], "success": true, "result": `+result+`}`)
+	}
+
+	current := ResourceItems{
+		{
+			"id":               float64(1),
+			"name":             "http-requests",
+			"dataset":          "http_requests",
+			"enabled":          true,
+			"destination_conf": "s3://logs/http?region=eu-west-2",
+			"logpull_options":  "fields=ClientIP,EdgeStartTimestamp,RayID&timestamps=rfc3339",
+			"frequency":        "high",
+		},
+	}
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		logbuf = gbytes.NewBuffer()
+		cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("LogpushJobsResource", func() {
+		It("should key jobs by name, or dataset and destination", func() {
+			Expect(LogpushJobsResource.Key(current[0])).To(Equal("http-requests"))
+			Expect(LogpushJobsResource.Key(ResourceItem{
+				"dataset":          "firewall_events",
+				"destination_conf": "https://logs.example.com/cloudflare",
+			})).To(Equal("firewall_events https://logs.example.com/cloudflare"))
+		})
+	})
+
+	Describe("UpdateLogpushJobs()", func() {
+		It("should log the fields that are added and removed", func() {
+			update, err := CompareResourceItemsForUpdate(LogpushJobsResource, current, ResourceItems{
+				{
+					"name":            "http-requests",
+					"logpull_options": "fields=ClientIP,RayID,ClientRequestHost&timestamps=rfc3339",
+					"frequency":       "low",
+				},
+			})
+			Expect(err).To(BeNil())
+
+			Expect(cloudFlare.UpdateLogpushJobs("123", update, nil, true)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+
+			Expect(logbuf).To(gbytes.Say(`Would have changed logpush job "http-requests" adding fields ClientRequestHost, removing fields EdgeStartTimestamp, from {"frequency":"high"} to {"frequency":"low"}`))
+		})
+
+		It("should log fields in output options that are reordered", func() {
+			update, err := CompareResourceItemsForUpdate(LogpushJobsResource, ResourceItems{
+				{
+					"id":             float64(2),
+					"name":           "firewall-events",
+					"output_options": map[string]interface{}{"field_names": []interface{}{"Action", "RayID"}, "timestamp_format": "rfc3339"},
+				},
+			}, ResourceItems{
+				{
+					"name":           "firewall-events",
+					"output_options": map[string]interface{}{"field_names": []interface{}{"RayID", "Action"}},
+				},
+			})
+			Expect(err).To(BeNil())
+
+			Expect(cloudFlare.UpdateLogpushJobs("123", update, nil, true)).To(BeNil())
+			Expect(logbuf).To(gbytes.Say(`Would have changed logpush job "firewall-events" reordering fields to RayID, Action\n`))
+		})
+
+		It("should request an ownership challenge for a new destination without a valid token", func() {
+			update, err := CompareResourceItemsForUpdate(LogpushJobsResource, current, ResourceItems{
+				{"name": "http-requests", "destination_conf": "s3://new-logs/http?region=eu-west-2"},
+			})
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/logpush/ownership/validate"),
+					ghttp.VerifyJSON(`{"destination_conf": "s3://new-logs/http?region=eu-west-2", "ownership_challenge": "stale"}`),
+					respondWithItem(`{"valid": false}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/logpush/ownership"),
+					ghttp.VerifyJSON(`{"destination_conf": "s3://new-logs/http?region=eu-west-2"}`),
+					respondWithItem(`{"filename": "http/ownership-challenge-bb2912e0.txt", "valid": true}`),
+				),
+			)
+
+			err = cloudFlare.UpdateLogpushJobs("123", update, []string{"stale"}, false)
+
+			Expect(err).To(MatchError("Ownership challenge needed for 1 destinations, pass the token from each file with --challenge"))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+			Expect(logbuf).To(gbytes.Say(`Wrote ownership challenge for destination "s3://new-logs/http\?region=eu-west-2" to "http/ownership-challenge-bb2912e0.txt"`))
+		})
+
+		It("should create jobs with the token that is valid for their destination", func() {
+			update, err := CompareResourceItemsForUpdate(LogpushJobsResource, current, append(current, ResourceItem{
+				"name":             "firewall-events",
+				"dataset":          "firewall_events",
+				"destination_conf": "gs://logs/firewall",
+			}))
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/logpush/ownership/validate"),
+					ghttp.VerifyJSON(`{"destination_conf": "gs://logs/firewall", "ownership_challenge": "abc"}`),
+					respondWithItem(`{"valid": false}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/logpush/ownership/validate"),
+					ghttp.VerifyJSON(`{"destination_conf": "gs://logs/firewall", "ownership_challenge": "def"}`),
+					respondWithItem(`{"valid": true}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/logpush/jobs"),
+					ghttp.VerifyJSON(`{
+						"name": "firewall-events",
+						"dataset": "firewall_events",
+						"destination_conf": "gs://logs/firewall",
+						"ownership_challenge": "def"
+					}`),
+					respondWithItem(`{"id": 2}`),
+				),
+			)
+
+			Expect(cloudFlare.UpdateLogpushJobs("123", update, []string{"abc", "def"}, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+		})
+
+		It("should not need a challenge for destinations that aren't verified by one", func() {
+			update, err := CompareResourceItemsForUpdate(LogpushJobsResource, ResourceItems{}, ResourceItems{
+				{"name": "http-requests", "dataset": "http_requests", "destination_conf": "https://logs.example.com/cloudflare"},
+			})
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/logpush/jobs"),
+					respondWithItem(`{"id": 3}`),
+				),
+			)
+
+			Expect(cloudFlare.UpdateLogpushJobs("123", update, nil, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+})
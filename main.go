@@ -1,9 +1,15 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/jwaldrip/odin.v1/cli"
 )
@@ -19,6 +25,31 @@ func init() {
 	zones := app.DefineSubCommand("zones", "List available zones by name and ID", zones)
 	zones.InheritFlags("email", "key")
 
+	zoneCreate := zones.DefineSubCommand("create", "Add zone", zonesCreate)
+	zoneCreate.InheritFlags("email", "key")
+	zoneCreate.DefineParams("name")
+	zoneCreate.DefineStringFlag("account", "", "ID of account to add zone to (default: user's account)")
+	zoneCreate.DefineBoolFlag("jump-start", false, "Scan for existing DNS records and import them")
+	zoneCreate.DefineStringFlag("type", "full", "Zone type: full, or partial for CNAME setup")
+
+	zonePause := zones.DefineSubCommand("pause", "Stop proxying traffic for zone", func(cmd cli.Command) { zonesPause(cmd, true) })
+	zonePause.InheritFlags("email", "key")
+	zonePause.DefineParams("zone_id")
+
+	zoneUnpause := zones.DefineSubCommand("unpause", "Start proxying traffic for zone again", func(cmd cli.Command) { zonesPause(cmd, false) })
+	zoneUnpause.InheritFlags("email", "key")
+	zoneUnpause.DefineParams("zone_id")
+
+	zoneDelete := zones.DefineSubCommand("delete", "Delete zone, after typing its name to confirm", zonesDelete)
+	zoneDelete.InheritFlags("email", "key")
+	zoneDelete.DefineParams("zone_id")
+	zoneDelete.DefineStringFlag("confirm", "", "Zone name, to confirm deletion without being asked")
+
+	zoneActivation := zones.DefineSubCommand("activation", "Show zone status and name servers to change to", zonesActivation)
+	zoneActivation.InheritFlags("email", "key")
+	zoneActivation.DefineParams("zone_id")
+	zoneActivation.DefineBoolFlag("check", false, "Ask CloudFlare to check name servers now")
+
 	download := app.DefineSubCommand("download", "Download configuration to file", download)
 	download.InheritFlags("email", "key")
 	download.DefineParams("zone_id", "file")
@@ -27,6 +58,257 @@ func init() {
 	upload.InheritFlags("email", "key")
 	upload.DefineParams("zone_id", "file")
 	upload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	upload.DefineStringFlag("policy", "", "Refuse to upload config that violates policy file")
+	upload.DefineFlag(&stringList{}, "allow-protected", "Allow changes to a key protected by the policy file")
+	upload.DefineIntFlag("max-changes", 0, "Refuse to upload more than this many changes")
+	upload.DefineBoolFlag("purge-after", false, "Purge everything from cache after uploading changes")
+	defineAuditFlags(upload)
+	defineLockFlags(upload)
+
+	plan := app.DefineSubCommand("plan", "Log changes that upload would make", plan)
+	plan.InheritFlags("email", "key")
+	plan.DefineParams("zone_id", "file")
+	plan.DefineStringFlag("policy", "", "Refuse to plan config that violates policy file")
+
+	pageRules := app.DefineSubCommand("pagerules", "Manage page rules", exitWithUsage)
+	pageRules.InheritFlags("email", "key")
+	defineResourceCommands(pageRules, PageRulesResource)
+
+	dns := app.DefineSubCommand("dns", "Manage DNS records", exitWithUsage)
+	dns.InheritFlags("email", "key")
+	defineResourceCommands(dns, DNSRecordsResource)
+
+	dnsExport := dns.DefineSubCommand("export", "Export DNS records to BIND zone file", dnsExport)
+	dnsExport.InheritFlags("email", "key")
+	dnsExport.DefineParams("zone_id", "zone_file")
+
+	dnsImport := dns.DefineSubCommand("import", "Convert BIND zone file to DNS records file for upload", dnsImport)
+	dnsImport.DefineParams("zone_file", "file")
+	dnsImport.DefineStringFlag("origin", "", "Zone name, if the zone file doesn't set $ORIGIN")
+
+	firewall := app.DefineSubCommand("firewall", "Manage IP access rules, firewall rules, zone lockdowns and user-agent rules", exitWithUsage)
+	firewall.InheritFlags("email", "key")
+
+	accessRules := firewall.DefineSubCommand("access-rules", "Manage IP access rules", exitWithUsage)
+	accessRules.InheritFlags("email", "key")
+	defineResourceCommands(accessRules, AccessRulesResource)
+
+	firewallRules := firewall.DefineSubCommand("rules", "Manage firewall rules", exitWithUsage)
+	firewallRules.InheritFlags("email", "key")
+	defineResourceCommands(firewallRules, FirewallRulesResource)
+
+	lockdowns := firewall.DefineSubCommand("lockdowns", "Manage zone lockdown rules", exitWithUsage)
+	lockdowns.InheritFlags("email", "key")
+	defineResourceCommands(lockdowns, LockdownsResource)
+
+	userAgentRules := firewall.DefineSubCommand("ua-rules", "Manage user-agent blocking rules", exitWithUsage)
+	userAgentRules.InheritFlags("email", "key")
+	defineResourceCommands(userAgentRules, UserAgentRulesResource)
+
+	customPages := app.DefineSubCommand("custompages", "Manage custom error and challenge pages", exitWithUsage)
+	customPages.InheritFlags("email", "key")
+	defineResourceCommands(customPages, CustomPagesResource)
+
+	customHostnames := app.DefineSubCommand("customhostnames", "Manage custom hostnames for SSL for SaaS", exitWithUsage)
+	customHostnames.InheritFlags("email", "key")
+	defineResourceCommands(customHostnames, CustomHostnamesResource)
+
+	customHostnamesStatus := customHostnames.DefineSubCommand("status", "Show verification and certificate status, and records that customers must create", customHostnamesStatus)
+	customHostnamesStatus.InheritFlags("email", "key")
+	customHostnamesStatus.DefineParams("zone_id")
+
+	rateLimits := app.DefineSubCommand("ratelimits", "Manage rate limiting rules", exitWithUsage)
+	rateLimits.InheritFlags("email", "key")
+	defineResourceCommands(rateLimits, RateLimitsResource)
+
+	healthchecks := app.DefineSubCommand("healthchecks", "Manage standalone healthchecks", exitWithUsage)
+	healthchecks.InheritFlags("email", "key")
+	defineResourceCommands(healthchecks, HealthchecksResource)
+
+	healthchecksPlan := healthchecks.DefineSubCommand("plan", "Log changes that upload would make, and show current health", healthchecksPlan)
+	healthchecksPlan.InheritFlags("email", "key")
+	healthchecksPlan.DefineParams("zone_id", "file")
+
+	healthchecksStatus := healthchecks.DefineSubCommand("status", "Show current health of healthchecks", healthchecksStatus)
+	healthchecksStatus.InheritFlags("email", "key")
+	healthchecksStatus.DefineParams("zone_id")
+
+	logpush := app.DefineSubCommand("logpush", "Manage Logpush jobs", exitWithUsage)
+	logpush.InheritFlags("email", "key")
+
+	logpushDownload := logpush.DefineSubCommand("download", "Download logpush jobs to file",
+		func(cmd cli.Command) { downloadResource(cmd, LogpushJobsResource) })
+	logpushDownload.InheritFlags("email", "key")
+	logpushDownload.DefineParams("zone_id", "file")
+
+	logpushUpload := logpush.DefineSubCommand("upload", "Upload logpush jobs from file", logpushUpload)
+	logpushUpload.InheritFlags("email", "key")
+	logpushUpload.DefineParams("zone_id", "file")
+	logpushUpload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	logpushUpload.DefineFlag(&stringList{}, "challenge", "Ownership challenge token for new destination")
+	defineAuditFlags(logpushUpload)
+	defineLockFlags(logpushUpload)
+
+	rulesets := app.DefineSubCommand("rulesets", "Manage rules in ruleset phases", exitWithUsage)
+	rulesets.InheritFlags("email", "key")
+
+	rulesetsDownload := rulesets.DefineSubCommand("download", "Download phase rulesets to file", rulesetsDownload)
+	rulesetsDownload.InheritFlags("email", "key")
+	rulesetsDownload.DefineParams("zone_id", "file")
+	rulesetsDownload.DefineFlag(&stringList{}, "phase", "Phase to download (default: all supported phases)")
+
+	rulesetsUpload := rulesets.DefineSubCommand("upload", "Upload phase rulesets from file", rulesetsUpload)
+	rulesetsUpload.InheritFlags("email", "key")
+	rulesetsUpload.DefineParams("zone_id", "file")
+	rulesetsUpload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	defineAuditFlags(rulesetsUpload)
+	defineLockFlags(rulesetsUpload)
+
+	purge := app.DefineSubCommand("purge", "Purge URLs, or other content, from cache", purge)
+	purge.InheritFlags("email", "key")
+	purge.DefineParams("zone_id")
+	purge.DefineBoolFlag("everything", false, "Purge everything from cache")
+	purge.DefineStringFlag("files-from", "", "File of URLs to purge, one per line")
+	purge.DefineFlag(&stringList{}, "tag", "Purge cache tag")
+	purge.DefineFlag(&stringList{}, "host", "Purge hostname")
+	purge.DefineFlag(&stringList{}, "prefix", "Purge URL prefix")
+	purge.DefineBoolFlag("yes", false, "Don't ask for confirmation before purging everything")
+	purge.DefineBoolFlag("dry-run", false, "Log what would be purged without purging it")
+
+	certs := app.DefineSubCommand("certs", "Manage custom SSL certificates", exitWithUsage)
+	certs.InheritFlags("email", "key")
+
+	certsUpload := certs.DefineSubCommand("upload", "Upload custom certificate and private key", certsUpload)
+	certsUpload.InheritFlags("email", "key")
+	certsUpload.DefineParams("zone_id", "cert_file", "key_file")
+	certsUpload.DefineStringFlag("replace", "", "ID of certificate to replace when rotating")
+	certsUpload.DefineStringFlag("bundle-method", "", "How to bundle intermediate certificates: ubiquitous, optimal or force")
+
+	certsReport := certs.DefineSubCommand("report", "List custom certificates and days to expiry for zones (default: all)", certsReport)
+	certsReport.InheritFlags("email", "key")
+	certsReport.DefineIntFlag("threshold", 30, "Exit non-zero if a certificate expires within this many days")
+
+	originCA := app.DefineSubCommand("origin-ca", "Manage Origin CA certificates", exitWithUsage)
+	originCA.InheritFlags("email", "key")
+
+	originCAIssue := originCA.DefineSubCommand("issue", "Generate key and CSR locally and issue certificate for hostnames", originCAIssue)
+	originCAIssue.InheritFlags("email", "key")
+	originCAIssue.DefineParams("cert_file", "key_file", "hostname")
+	originCAIssue.DefineStringFlag("type", "rsa", "Type of private key: rsa or ecdsa")
+	originCAIssue.DefineIntFlag("validity", 5475, "Days that the certificate is valid for")
+
+	originCAList := originCA.DefineSubCommand("list", "List Origin CA certificates for zone", originCAList)
+	originCAList.InheritFlags("email", "key")
+	originCAList.DefineParams("zone_id")
+
+	originCARevoke := originCA.DefineSubCommand("revoke", "Revoke Origin CA certificate", originCARevoke)
+	originCARevoke.InheritFlags("email", "key")
+	originCARevoke.DefineParams("cert_id")
+
+	loadBalancers := app.DefineSubCommand("loadbalancers", "Manage load balancers, pools and monitors", exitWithUsage)
+	loadBalancers.InheritFlags("email", "key")
+
+	loadBalancersDownload := loadBalancers.DefineSubCommand("download", "Download account's monitors and pools, and zone's load balancers, to file", loadBalancersDownload)
+	loadBalancersDownload.InheritFlags("email", "key")
+	loadBalancersDownload.DefineParams("account_id", "zone_id", "file")
+
+	loadBalancersUpload := loadBalancers.DefineSubCommand("upload", "Upload monitors, pools and load balancers from file", loadBalancersUpload)
+	loadBalancersUpload.InheritFlags("email", "key")
+	loadBalancersUpload.DefineParams("account_id", "zone_id", "file")
+	loadBalancersUpload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	loadBalancersUpload.DefineBoolFlag("prune", false, "Delete account's monitors and pools that aren't in file")
+	defineAuditFlags(loadBalancersUpload)
+	defineLockFlags(loadBalancersUpload)
+
+	workers := app.DefineSubCommand("workers", "Manage Workers scripts and routes", exitWithUsage)
+	workers.InheritFlags("email", "key")
+
+	workersDownload := workers.DefineSubCommand("download", "Download zone's worker routes, and the scripts they use, to file", workersDownload)
+	workersDownload.InheritFlags("email", "key")
+	workersDownload.DefineParams("account_id", "zone_id", "file")
+
+	workersUpload := workers.DefineSubCommand("upload", "Deploy changed scripts and upload worker routes from file", workersUpload)
+	workersUpload.InheritFlags("email", "key")
+	workersUpload.DefineParams("account_id", "zone_id", "file")
+	workersUpload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	defineAuditFlags(workersUpload)
+	defineLockFlags(workersUpload)
+
+	workersPlan := workers.DefineSubCommand("plan", "Log changes that upload would make", workersPlan)
+	workersPlan.InheritFlags("email", "key")
+	workersPlan.DefineParams("account_id", "zone_id", "file")
+
+	members := app.DefineSubCommand("members", "Manage account members and their roles", exitWithUsage)
+	members.InheritFlags("email", "key")
+
+	membersDownload := members.DefineSubCommand("download", "Download account members and their roles to file", membersDownload)
+	membersDownload.InheritFlags("email", "key")
+	membersDownload.DefineParams("account_id", "file")
+
+	membersUpload := members.DefineSubCommand("upload", "Log plan, then invite members and change roles from file", membersUpload)
+	membersUpload.InheritFlags("email", "key")
+	membersUpload.DefineParams("account_id", "file")
+	membersUpload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	membersUpload.DefineBoolFlag("prune", false, "Remove members that aren't in file")
+	membersUpload.DefineBoolFlag("yes", false, "Don't ask for confirmation before making changes")
+	defineAuditFlags(membersUpload)
+	defineLockFlags(membersUpload)
+
+	membersPlan := members.DefineSubCommand("plan", "Log changes that upload would make", membersPlan)
+	membersPlan.InheritFlags("email", "key")
+	membersPlan.DefineParams("account_id", "file")
+	membersPlan.DefineBoolFlag("prune", false, "Include removal of members that aren't in file")
+
+	notifications := app.DefineSubCommand("notifications", "Manage notification policies and webhooks", exitWithUsage)
+	notifications.InheritFlags("email", "key")
+
+	notificationsDownload := notifications.DefineSubCommand("download", "Download account's webhooks and notification policies to file", notificationsDownload)
+	notificationsDownload.InheritFlags("email", "key")
+	notificationsDownload.DefineParams("account_id", "file")
+
+	notificationsUpload := notifications.DefineSubCommand("upload", "Upload webhooks and notification policies from file", notificationsUpload)
+	notificationsUpload.InheritFlags("email", "key")
+	notificationsUpload.DefineParams("account_id", "file")
+	notificationsUpload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	notificationsUpload.DefineBoolFlag("rotate-secrets", false, "Send the secrets of webhooks that haven't changed")
+	defineAuditFlags(notificationsUpload)
+	defineLockFlags(notificationsUpload)
+
+	notificationsPlan := notifications.DefineSubCommand("plan", "Log changes that upload would make", notificationsPlan)
+	notificationsPlan.InheritFlags("email", "key")
+	notificationsPlan.DefineParams("account_id", "file")
+	notificationsPlan.DefineBoolFlag("rotate-secrets", false, "Send the secrets of webhooks that haven't changed")
+
+	lists := app.DefineSubCommand("lists", "Manage account IP and redirect lists", exitWithUsage)
+	lists.InheritFlags("email", "key")
+
+	listsDownload := lists.DefineSubCommand("download", "Download list items to CSV or JSON file", listsDownload)
+	listsDownload.InheritFlags("email", "key")
+	listsDownload.DefineParams("account_id", "list_name", "file")
+
+	listsUpload := lists.DefineSubCommand("upload", "Replace list items from CSV or JSON file, creating list if needed", listsUpload)
+	listsUpload.InheritFlags("email", "key")
+	listsUpload.DefineParams("account_id", "list_name", "file")
+	listsUpload.DefineStringFlag("kind", "", "Kind of list to create: ip or redirect (default: kind of existing list)")
+	listsUpload.DefineStringFlag("description", "", "Description of list to create")
+	listsUpload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	listsUpload.DefineDurationFlag("timeout", 5*time.Minute, "How long to wait for the bulk operation to complete")
+	defineAuditFlags(listsUpload)
+	defineLockFlags(listsUpload)
+
+	validate := app.DefineSubCommand("validate", "Check configuration files offline against known settings", validate)
+	validate.DefineParams("file")
+
+	audit := app.DefineSubCommand("audit", "Query changes recorded in audit file", audit)
+	audit.DefineParams("audit_file")
+	audit.DefineStringFlag("zone", "", "Only show changes to zone ID or name")
+	audit.DefineStringFlag("setting", "", "Only show changes to setting")
+	audit.DefineStringFlag("since", "", "Only show changes on or after date (YYYY-MM-DD or RFC 3339)")
+	audit.DefineStringFlag("until", "", "Only show changes before date (YYYY-MM-DD is inclusive)")
+
+	policy := app.DefineSubCommand("policy", "Evaluate policy files", exitWithUsage)
+	policyCheck := policy.DefineSubCommand("check", "Check configuration file against policy file", policyCheck)
+	policyCheck.DefineParams("policy_file", "file")
 }
 
 func main() {
@@ -54,6 +336,180 @@ func getRequiredFlag(cmd cli.Command, name string) string {
 	return val
 }
 
+func getPolicyFlag(cmd cli.Command) *Policy {
+	file := cmd.Flag("policy").String()
+	if file == "" {
+		return nil
+	}
+
+	policy, err := LoadPolicy(file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return policy
+}
+
+// defineResourceCommands adds download and upload subcommands to parent for
+// a collection of resources.
+func defineResourceCommands(parent *cli.SubCommand, resource ResourceType) {
+	download := parent.DefineSubCommand("download", fmt.Sprintf("Download %ss to file", resource.Name),
+		func(cmd cli.Command) { downloadResource(cmd, resource) })
+	download.InheritFlags("email", "key")
+	download.DefineParams("zone_id", "file")
+
+	upload := parent.DefineSubCommand("upload", fmt.Sprintf("Upload %ss from file", resource.Name),
+		func(cmd cli.Command) { uploadResource(cmd, resource) })
+	upload.InheritFlags("email", "key")
+	upload.DefineParams("zone_id", "file")
+	upload.DefineBoolFlag("dry-run", false, "Log changes without actioning them")
+	defineAuditFlags(upload)
+	defineLockFlags(upload)
+}
+
+func defineAuditFlags(cmd *cli.SubCommand) {
+	cmd.DefineStringFlag("audit-log", "", "Append applied changes to JSON Lines audit file")
+	cmd.DefineStringFlag("operator", "", "Operator identity for audit file (default: email)")
+	cmd.DefineBoolFlag("audit-git", false, "Record git commit of configuration file in audit file")
+}
+
+func defineLockFlags(cmd *cli.SubCommand) {
+	cmd.DefineStringFlag("lock-dir", filepath.Join(os.TempDir(), "cloudflare-configure"), "Directory of per-zone lock files")
+	cmd.DefineDurationFlag("lock-wait", 0, "How long to wait for another process to release the zone lock")
+	cmd.DefineDurationFlag("lock-stale", time.Hour, "Age at which a zone lock is considered abandoned")
+}
+
+// getZoneLock takes the lock for a zone before any changes are made to it.
+// If the process exits without releasing it then the lock becomes stale.
+func getZoneLock(cmd cli.Command, zone string) *ZoneLock {
+	lock, err := AcquireZoneLock(
+		cmd.Flag("lock-dir").String(),
+		zone,
+		cmd.Flag("lock-wait").Get().(time.Duration),
+		cmd.Flag("lock-stale").Get().(time.Duration),
+	)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return lock
+}
+
+// withZoneLock runs fn while holding the lock for a zone, or without it if
+// logOnly is true. The lock is released before exiting if fn fails.
+func withZoneLock(cmd cli.Command, zone string, logOnly bool, fn func() error) {
+	var lock *ZoneLock
+	if !logOnly {
+		lock = getZoneLock(cmd, zone)
+	}
+
+	err := fn()
+	lock.Release()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func getAuditJournal(cmd cli.Command, cloudflare *CloudFlare, zoneID string) *AuditJournal {
+	if cmd.Flag("audit-log").String() == "" {
+		return nil
+	}
+
+	zone, err := cloudflare.Zone(zoneID)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	journal := newAuditJournal(cmd, cloudflare, zone.Name)
+	journal.ZoneID = zoneID
+
+	return journal
+}
+
+// getAccountAuditJournal is the same as getAuditJournal for changes that
+// are made to accounts instead of zones.
+func getAccountAuditJournal(cmd cli.Command, cloudflare *CloudFlare, accountID string) *AuditJournal {
+	if cmd.Flag("audit-log").String() == "" {
+		return nil
+	}
+
+	account, err := cloudflare.Account(accountID)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return newAuditJournal(cmd, cloudflare, account.Name)
+}
+
+func newAuditJournal(cmd cli.Command, cloudflare *CloudFlare, name string) *AuditJournal {
+	operator := cmd.Flag("operator").String()
+	if operator == "" {
+		operator = cloudflare.Query.AuthEmail
+	}
+
+	journal := &AuditJournal{
+		File:     cmd.Flag("audit-log").String(),
+		Operator: operator,
+		ZoneName: name,
+	}
+
+	if cmd.Flag("audit-git").Get() == true {
+		commit, err := ConfigFileCommit(cmd.Param("file").String())
+		if err != nil {
+			log.Fatalln("Unable to find git commit of config:", err)
+		}
+		journal.ConfigCommit = commit
+	}
+
+	return journal
+}
+
+// getDateFlag parses a date or timestamp. Dates are treated as the end of
+// the day when endOfDay is true, so that ranges include the whole day.
+func getDateFlag(cmd cli.Command, name string, endOfDay bool) time.Time {
+	val := cmd.Flag(name).String()
+	if val == "" {
+		return time.Time{}
+	}
+
+	if t, err := time.Parse(time.RFC3339, val); err == nil {
+		return t
+	}
+
+	t, err := time.Parse("2006-01-02", val)
+	if err != nil {
+		fmt.Print("invalid date for flag: ", name, "\n\n")
+		exitWithUsage(cmd)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t
+}
+
+// stringList is a flag value that may be given more than once or as a
+// comma-separated list.
+type stringList []string
+
+func (s *stringList) Get() interface{} {
+	return []string(*s)
+}
+
+func (s *stringList) Set(val string) error {
+	for _, item := range strings.Split(val, ",") {
+		if item != "" {
+			*s = append(*s, item)
+		}
+	}
+
+	return nil
+}
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
 func exitWithUsage(cmd cli.Command) {
 	cmd.Usage()
 	os.Exit(2)
@@ -71,6 +527,87 @@ func zones(cmd cli.Command) {
 	}
 }
 
+func zonesCreate(cmd cli.Command) {
+	cloudflare := setup(cmd)
+
+	create := CloudFlareZoneCreate{
+		Name:      cmd.Param("name").String(),
+		JumpStart: (cmd.Flag("jump-start").Get() == true),
+		Type:      cmd.Flag("type").String(),
+	}
+	if account := cmd.Flag("account").String(); account != "" {
+		create.Account = &CloudFlareZoneCreateAccount{ID: account}
+	}
+
+	log.Println("Creating zone:", create.Name)
+	zone, err := cloudflare.CreateZone(create)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := WriteZoneActivation(os.Stdout, zone); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func zonesPause(cmd cli.Command, paused bool) {
+	cloudflare := setup(cmd)
+	zoneID := cmd.Param("zone_id").String()
+
+	if paused {
+		log.Println("Pausing zone:", zoneID)
+	} else {
+		log.Println("Unpausing zone:", zoneID)
+	}
+
+	if _, err := cloudflare.PauseZone(zoneID, paused); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func zonesDelete(cmd cli.Command) {
+	cloudflare := setup(cmd)
+
+	zone, err := cloudflare.Zone(cmd.Param("zone_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	name := cmd.Flag("confirm").String()
+	if name == "" {
+		name = prompt(fmt.Sprintf("Type the name of zone %s to delete it:", zone.ID))
+	}
+	if name != zone.Name {
+		log.Fatalf("Not deleting zone %s, name %q doesn't match %q", zone.ID, name, zone.Name)
+	}
+
+	log.Println("Deleting zone:", zone.Name)
+	if err := cloudflare.DeleteZone(zone.ID); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func zonesActivation(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	zoneID := cmd.Param("zone_id").String()
+
+	if cmd.Flag("check").Get() == true {
+		log.Println("Requesting activation check for zone:", zoneID)
+		if err := cloudflare.CheckZoneActivation(zoneID); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	zone, err := cloudflare.Zone(zoneID)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := WriteZoneActivation(os.Stdout, zone); err != nil {
+		log.Fatalln(err)
+	}
+}
+
 func download(cmd cli.Command) {
 	cloudflare := setup(cmd)
 	settings, err := cloudflare.Settings(cmd.Param("zone_id").String())
@@ -87,29 +624,943 @@ func download(cmd cli.Command) {
 	}
 }
 
+func compareZoneConfig(cmd cli.Command, cloudflare *CloudFlare, zone string, policy *Policy) (ConfigItemsForUpdate, error) {
+	settings, err := cloudflare.Settings(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	configActual := settings.ConfigItems()
+	configDesired, err := LoadConfigItems(cmd.Param("file").String())
+	if err != nil {
+		return nil, err
+	}
+
+	if policy != nil {
+		if violations := policy.Check(configDesired); len(violations) > 0 {
+			return nil, violations
+		}
+	}
+
+	configUpdate, err := CompareConfigItemsForUpdate(configActual, configDesired)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy != nil {
+		if violations := policy.CheckUpdate(configUpdate); len(violations) > 0 {
+			return nil, violations
+		}
+	}
+
+	return configUpdate, nil
+}
+
 func upload(cmd cli.Command) {
 	cloudflare := setup(cmd)
 	zone := cmd.Param("zone_id").String()
+	logOnly := (cmd.Flag("dry-run").Get() == true)
 
-	settings, err := cloudflare.Settings(zone)
+	policy := getPolicyFlag(cmd)
+	var protected []string
+	if policy != nil {
+		protected = policy.Protected
+	}
+
+	if !logOnly {
+		cloudflare.Journal = getAuditJournal(cmd, cloudflare, zone)
+	}
+
+	withZoneLock(cmd, zone, logOnly, func() error {
+		configUpdate, err := compareZoneConfig(cmd, cloudflare, zone, policy)
+		if err != nil {
+			return err
+		}
+
+		allowed := cmd.Flag("allow-protected").Get().([]string)
+		if err := CheckProtectedKeys(configUpdate, protected, allowed); err != nil {
+			return err
+		}
+
+		maxChanges := cmd.Flag("max-changes").Get().(int)
+		if err := CheckMaxChanges(configUpdate, maxChanges); err != nil {
+			return err
+		}
+
+		err = cloudflare.Update(zone, configUpdate, logOnly)
+		if err == nil && len(configUpdate) > 0 && cmd.Flag("purge-after").Get() == true {
+			err = cloudflare.Purge(zone, PurgeRequest{Everything: true}, logOnly)
+		}
+
+		return err
+	})
+}
+
+func plan(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	zone := cmd.Param("zone_id").String()
+	configUpdate, err := compareZoneConfig(cmd, cloudflare, zone, getPolicyFlag(cmd))
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	configActual := settings.ConfigItems()
-	configDesired, err := LoadConfigItems(cmd.Param("file").String())
+	err = cloudflare.Update(zone, configUpdate, true)
 	if err != nil {
 		log.Fatalln(err)
 	}
+}
 
-	configUpdate, err := CompareConfigItemsForUpdate(configActual, configDesired)
+func downloadResource(cmd cli.Command, resource ResourceType) {
+	cloudflare := setup(cmd)
+	items, err := cloudflare.ResourceItems(resource, cmd.Param("zone_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if resource.Order != nil {
+		items = resource.Order(items)
+	}
+
+	file := cmd.Param("file").String()
+	log.Println("Saving config to:", file)
+
+	err = SaveResourceItems(resource.Strip(items), file)
 	if err != nil {
 		log.Fatalln(err)
 	}
+}
 
+func uploadResource(cmd cli.Command, resource ResourceType) {
+	cloudflare := setup(cmd)
+	zone := cmd.Param("zone_id").String()
 	logOnly := (cmd.Flag("dry-run").Get() == true)
-	err = cloudflare.Update(zone, configUpdate, logOnly)
+
+	expected, err := LoadResourceItems(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if resource.Prepare != nil {
+		expected, err = resource.Prepare(expected)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if !logOnly {
+		cloudflare.Journal = getAuditJournal(cmd, cloudflare, zone)
+	}
+
+	withZoneLock(cmd, zone, logOnly, func() error {
+		current, err := cloudflare.ResourceItems(resource, zone)
+		if err != nil {
+			return err
+		}
+
+		update, err := CompareResourceItemsForUpdate(resource, current, expected)
+		if err != nil {
+			return err
+		}
+
+		return cloudflare.UpdateResource(resource, zone, update, logOnly)
+	})
+}
+
+func healthchecksPlan(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	zone := cmd.Param("zone_id").String()
+
+	current, err := cloudflare.ResourceItems(HealthchecksResource, zone)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	expected, err := LoadResourceItems(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	update, err := CompareResourceItemsForUpdate(HealthchecksResource, current, expected)
 	if err != nil {
 		log.Fatalln(err)
 	}
+
+	if err := cloudflare.UpdateResource(HealthchecksResource, zone, update, true); err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := WriteHealthcheckReport(os.Stdout, current); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func healthchecksStatus(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	items, err := cloudflare.ResourceItems(HealthchecksResource, cmd.Param("zone_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := WriteHealthcheckReport(os.Stdout, items); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func logpushUpload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	zone := cmd.Param("zone_id").String()
+	logOnly := (cmd.Flag("dry-run").Get() == true)
+
+	expected, err := LoadResourceItems(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if !logOnly {
+		cloudflare.Journal = getAuditJournal(cmd, cloudflare, zone)
+	}
+
+	withZoneLock(cmd, zone, logOnly, func() error {
+		current, err := cloudflare.ResourceItems(LogpushJobsResource, zone)
+		if err != nil {
+			return err
+		}
+
+		update, err := CompareResourceItemsForUpdate(LogpushJobsResource, current, expected)
+		if err != nil {
+			return err
+		}
+
+		return cloudflare.UpdateLogpushJobs(zone, update, cmd.Flag("challenge").Get().([]string), logOnly)
+	})
+}
+
+func dnsExport(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	zoneID := cmd.Param("zone_id").String()
+
+	zone, err := cloudflare.Zone(zoneID)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	records, err := cloudflare.ResourceItems(DNSRecordsResource, zoneID)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	file := cmd.Param("zone_file").String()
+	log.Println("Saving zone file to:", file)
+
+	out, err := os.Create(file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer out.Close()
+
+	err = ExportZoneFile(out, zone.Name, zone.NameServers, records)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func dnsImport(cmd cli.Command) {
+	in, err := os.Open(cmd.Param("zone_file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer in.Close()
+
+	records, err := ImportZoneFile(in, cmd.Flag("origin").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	file := cmd.Param("file").String()
+	log.Println("Saving config to:", file)
+
+	err = SaveResourceItems(records, file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func rulesetsDownload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+
+	phases := cmd.Flag("phase").Get().([]string)
+	if len(phases) == 0 {
+		phases = RulesetPhases
+	}
+
+	rulesets, err := cloudflare.Rulesets(cmd.Param("zone_id").String(), phases)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for phase, rules := range rulesets {
+		rulesets[phase] = RulesetRulesResource.Strip(rules)
+	}
+
+	file := cmd.Param("file").String()
+	log.Println("Saving config to:", file)
+
+	err = SaveRulesets(rulesets, file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func rulesetsUpload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	zone := cmd.Param("zone_id").String()
+	logOnly := (cmd.Flag("dry-run").Get() == true)
+
+	expected, err := LoadRulesets(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if !logOnly {
+		cloudflare.Journal = getAuditJournal(cmd, cloudflare, zone)
+	}
+
+	withZoneLock(cmd, zone, logOnly, func() error {
+		var err error
+		current := Rulesets{}
+		for phase := range expected {
+			if current[phase], err = cloudflare.PhaseRuleset(zone, phase); err != nil {
+				return err
+			}
+		}
+
+		update, err := CompareRulesetsForUpdate(current, expected)
+		if err != nil {
+			return err
+		}
+
+		return cloudflare.UpdateRulesets(zone, update, logOnly)
+	})
+}
+
+func purge(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	zone := cmd.Param("zone_id").String()
+	logOnly := (cmd.Flag("dry-run").Get() == true)
+
+	request := PurgeRequest{
+		Everything: (cmd.Flag("everything").Get() == true),
+		Tags:       cmd.Flag("tag").Get().([]string),
+		Hosts:      cmd.Flag("host").Get().([]string),
+		Prefixes:   cmd.Flag("prefix").Get().([]string),
+	}
+	for _, arg := range cmd.Args() {
+		request.Files = append(request.Files, arg.String())
+	}
+
+	if file := cmd.Flag("files-from").String(); file != "" {
+		files, err := ReadPurgeList(file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		request.Files = append(request.Files, files...)
+	}
+
+	if request.Empty() {
+		fmt.Print("nothing to purge\n\n")
+		exitWithUsage(cmd)
+	}
+	if request.Everything && len(request.Files)+len(request.Tags)+len(request.Hosts)+len(request.Prefixes) > 0 {
+		log.Fatalln("--everything can't be combined with URLs, tags, hosts or prefixes")
+	}
+	if err := request.Validate(); err != nil {
+		log.Fatalln(err)
+	}
+
+	if request.Everything && !logOnly && cmd.Flag("yes").Get() != true {
+		if !confirm(fmt.Sprintf("Purge everything from cache for zone %s?", zone)) {
+			log.Fatalln("Not purging")
+		}
+	}
+
+	if err := cloudflare.Purge(zone, request, logOnly); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// confirm asks a yes or no question on stdin, defaulting to no.
+func confirm(question string) bool {
+	answer := strings.ToLower(prompt(fmt.Sprintf("%s [y/N]", question)))
+	return answer == "y" || answer == "yes"
+}
+
+// prompt asks a question and returns the answer from stdin.
+func prompt(question string) string {
+	fmt.Printf("%s ", question)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+func certsUpload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+
+	upload, err := LoadCustomCertificateUpload(
+		cmd.Param("cert_file").String(),
+		cmd.Param("key_file").String(),
+		cmd.Flag("bundle-method").String(),
+	)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	zone := cmd.Param("zone_id").String()
+	replace := cmd.Flag("replace").String()
+	if replace != "" {
+		log.Println("Replacing certificate:", replace)
+	} else {
+		log.Println("Uploading certificate")
+	}
+
+	cert, err := cloudflare.UploadCustomCertificate(zone, replace, upload)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	log.Printf("Certificate %s for %s expires on %s", cert.ID, strings.Join(cert.Hosts, ", "),
+		cert.ExpiresOn.Format("2006-01-02"))
+}
+
+func certsReport(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	threshold := cmd.Flag("threshold").Get().(int)
+
+	for _, arg := range cmd.Args() {
+		if strings.HasPrefix(arg.String(), "-") {
+			log.Fatalf("Flag %q must come before zone IDs", arg.String())
+		}
+	}
+
+	var zones []CloudFlareZoneItem
+	if len(cmd.Args()) == 0 {
+		var err error
+		if zones, err = cloudflare.Zones(); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	for _, arg := range cmd.Args() {
+		zone, err := cloudflare.Zone(arg.String())
+		if err != nil {
+			log.Fatalln(err)
+		}
+		zones = append(zones, zone)
+	}
+
+	expiring := 0
+	for _, zone := range zones {
+		certs, err := cloudflare.CustomCertificates(zone.ID)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		zoneExpiring, err := WriteCertificateReport(os.Stdout, zone.Name, certs, time.Now(), threshold)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		expiring += len(zoneExpiring)
+	}
+
+	if expiring > 0 {
+		log.Fatalf("%d certificates expire within %d days", expiring, threshold)
+	}
+}
+
+func originCAIssue(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	certFile := cmd.Param("cert_file").String()
+	keyFile := cmd.Param("key_file").String()
+
+	hostnames := []string{cmd.Param("hostname").String()}
+	for _, arg := range cmd.Args() {
+		hostnames = append(hostnames, arg.String())
+	}
+
+	if err := CheckFilesAbsent(certFile, keyFile); err != nil {
+		log.Fatalln(err)
+	}
+
+	request, key, err := NewOriginCertificateRequest(
+		cmd.Flag("type").String(),
+		hostnames,
+		cmd.Flag("validity").Get().(int),
+	)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	log.Println("Requesting certificate for:", strings.Join(hostnames, ", "))
+	cert, err := cloudflare.CreateOriginCertificate(request)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := WriteNewFile(keyFile, key, 0600); err != nil {
+		log.Fatalln(err)
+	}
+	if err := WriteNewFile(certFile, []byte(cert.Certificate), 0644); err != nil {
+		log.Fatalln(err)
+	}
+
+	log.Printf("Certificate %s expires on %s", cert.ID, cert.ExpiresOn)
+}
+
+func originCAList(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	certs, err := cloudflare.OriginCertificates(cmd.Param("zone_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for _, cert := range certs {
+		fmt.Println(cert.ID, "\t", cert.ExpiresOn, "\t", strings.Join(cert.Hostnames, ","))
+	}
+}
+
+func originCARevoke(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	id := cmd.Param("cert_id").String()
+
+	log.Println("Revoking certificate:", id)
+	if err := cloudflare.RevokeOriginCertificate(id); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func loadBalancersDownload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	config, err := cloudflare.LoadBalancerConfig(cmd.Param("account_id").String(), cmd.Param("zone_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	file := cmd.Param("file").String()
+	log.Println("Saving config to:", file)
+
+	err = SaveLoadBalancerConfig(config.Strip(), file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func loadBalancersUpload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	account := cmd.Param("account_id").String()
+	zone := cmd.Param("zone_id").String()
+	logOnly := (cmd.Flag("dry-run").Get() == true)
+
+	expected, err := LoadLoadBalancerConfig(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if !logOnly {
+		cloudflare.Journal = getAuditJournal(cmd, cloudflare, zone)
+	}
+
+	withZoneLock(cmd, zone, logOnly, func() error {
+		current, err := cloudflare.LoadBalancerConfig(account, zone)
+		if err != nil {
+			return err
+		}
+
+		update, err := CompareLoadBalancerConfigForUpdate(current, expected)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Flag("prune").Get() != true {
+			update = cloudflare.SkipLoadBalancerAccountDeletes(update)
+		}
+
+		return cloudflare.UpdateLoadBalancerConfig(account, zone, update, logOnly)
+	})
+}
+
+func workersDownload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	account := cmd.Param("account_id").String()
+	file := cmd.Param("file").String()
+
+	routes, err := cloudflare.ResourceItems(WorkerRoutesResource, cmd.Param("zone_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	config := WorkersConfig{Scripts: map[string]string{}, Routes: WorkerRoutesResource.Strip(routes)}
+	for _, route := range routes {
+		name, _ := route["script"].(string)
+		if name == "" || config.Scripts[name] != "" {
+			continue
+		}
+
+		content, err := cloudflare.WorkerScript(account, name)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if content == nil {
+			log.Fatalf("Worker script %q used by route %q doesn't exist", name, workerRouteKey(route))
+		}
+
+		script := fmt.Sprintf("%s.js", name)
+		log.Println("Saving script to:", filepath.Join(filepath.Dir(file), script))
+		if err := ioutil.WriteFile(filepath.Join(filepath.Dir(file), script), content, 0644); err != nil {
+			log.Fatalln(err)
+		}
+		config.Scripts[name] = script
+	}
+
+	log.Println("Saving config to:", file)
+	if err := SaveWorkersConfig(config, file); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func workersUpload(cmd cli.Command) {
+	updateWorkers(cmd, cmd.Flag("dry-run").Get() == true)
+}
+
+func workersPlan(cmd cli.Command) {
+	updateWorkers(cmd, true)
+}
+
+// updateWorkers deploys scripts before uploading routes, so that new routes
+// can use new scripts.
+func updateWorkers(cmd cli.Command, logOnly bool) {
+	cloudflare := setup(cmd)
+	account := cmd.Param("account_id").String()
+	zone := cmd.Param("zone_id").String()
+
+	config, err := LoadWorkersConfig(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if !logOnly {
+		cloudflare.Journal = getAuditJournal(cmd, cloudflare, zone)
+	}
+
+	withZoneLock(cmd, zone, logOnly, func() error {
+		scriptUpdate, err := cloudflare.CompareWorkerScriptsForUpdate(account, config.Scripts)
+		if err != nil {
+			return err
+		}
+
+		current, err := cloudflare.ResourceItems(WorkerRoutesResource, zone)
+		if err != nil {
+			return err
+		}
+
+		routeUpdate, err := CompareResourceItemsForUpdate(WorkerRoutesResource, current, config.Routes)
+		if err != nil {
+			return err
+		}
+
+		if err := cloudflare.UpdateWorkerScripts(account, scriptUpdate, logOnly); err != nil {
+			return err
+		}
+
+		return cloudflare.UpdateResource(WorkerRoutesResource, zone, routeUpdate, logOnly)
+	})
+}
+
+func membersDownload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	items, err := cloudflare.AccountMembers(cmd.Param("account_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	file := cmd.Param("file").String()
+	log.Println("Saving config to:", file)
+
+	err = SaveResourceItems(AccountMembersResource.Strip(items), file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func membersUpload(cmd cli.Command) {
+	updateMembers(cmd, cmd.Flag("dry-run").Get() == true)
+}
+
+func membersPlan(cmd cli.Command) {
+	updateMembers(cmd, true)
+}
+
+// updateMembers always logs the plan first. Unless logOnly is true, the
+// changes are then made once they've been confirmed.
+func updateMembers(cmd cli.Command, logOnly bool) {
+	cloudflare := setup(cmd)
+	account := cmd.Param("account_id").String()
+
+	expected, err := LoadResourceItems(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if !logOnly {
+		cloudflare.Journal = getAccountAuditJournal(cmd, cloudflare, account)
+	}
+
+	withZoneLock(cmd, account, logOnly, func() error {
+		current, err := cloudflare.AccountMembers(account)
+		if err != nil {
+			return err
+		}
+
+		roles, err := cloudflare.ResourceItems(AccountRolesResource, account)
+		if err != nil {
+			return err
+		}
+
+		update, err := CompareAccountMembersForUpdate(current, expected, roles)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Flag("prune").Get() != true {
+			update = cloudflare.SkipAccountMemberRemovals(update)
+		}
+
+		if err := cloudflare.UpdateAccountMembers(account, roles, update, true); err != nil {
+			return err
+		}
+
+		if logOnly || len(update) == 0 {
+			return nil
+		}
+
+		if cmd.Flag("yes").Get() != true && !confirm(fmt.Sprintf("Make %d changes to members of account %s?", len(update), account)) {
+			return errors.New("Not making changes")
+		}
+
+		return cloudflare.UpdateAccountMembers(account, roles, update, false)
+	})
+}
+
+func notificationsDownload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	config, err := cloudflare.NotificationConfig(cmd.Param("account_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	file := cmd.Param("file").String()
+	log.Println("Saving config to:", file)
+
+	if err := SaveNotificationConfig(config.Strip(), file); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func notificationsUpload(cmd cli.Command) {
+	updateNotifications(cmd, cmd.Flag("dry-run").Get() == true)
+}
+
+func notificationsPlan(cmd cli.Command) {
+	updateNotifications(cmd, true)
+}
+
+func updateNotifications(cmd cli.Command, logOnly bool) {
+	cloudflare := setup(cmd)
+	account := cmd.Param("account_id").String()
+
+	expected, err := LoadNotificationConfig(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if !logOnly {
+		cloudflare.Journal = getAccountAuditJournal(cmd, cloudflare, account)
+	}
+
+	withZoneLock(cmd, account, logOnly, func() error {
+		current, err := cloudflare.NotificationConfig(account)
+		if err != nil {
+			return err
+		}
+
+		update, err := CompareNotificationConfigForUpdate(current, expected, cmd.Flag("rotate-secrets").Get() == true)
+		if err != nil {
+			return err
+		}
+
+		return cloudflare.UpdateNotificationConfig(account, update, logOnly)
+	})
+}
+
+func listsDownload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	account := cmd.Param("account_id").String()
+	name := cmd.Param("list_name").String()
+
+	list, err := cloudflare.List(account, name)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if list == nil {
+		log.Fatalf("List %q doesn't exist", name)
+	}
+
+	items, err := cloudflare.ListItems(account, resourceID(list))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	file := cmd.Param("file").String()
+	log.Println("Saving list items to:", file)
+
+	if err := SaveListItems(items, resourceString(list, "kind"), file); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// listsUpload creates the list if it doesn't exist, and then replaces all
+// of its items in one bulk operation.
+func listsUpload(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	account := cmd.Param("account_id").String()
+	name := cmd.Param("list_name").String()
+	kind := cmd.Flag("kind").String()
+	logOnly := (cmd.Flag("dry-run").Get() == true)
+
+	if !logOnly {
+		cloudflare.Journal = getAccountAuditJournal(cmd, cloudflare, account)
+	}
+
+	withZoneLock(cmd, account, logOnly, func() error {
+		list, err := cloudflare.List(account, name)
+		if err != nil {
+			return err
+		}
+
+		current := ResourceItems{}
+		switch {
+		case list != nil && kind != "" && kind != resourceString(list, "kind"):
+			return fmt.Errorf("List %q is a %s list, not %s", name, resourceString(list, "kind"), kind)
+		case list != nil:
+			kind = resourceString(list, "kind")
+			if current, err = cloudflare.ListItems(account, resourceID(list)); err != nil {
+				return err
+			}
+		case kind == "":
+			return fmt.Errorf("List %q doesn't exist, use --kind to create it", name)
+		}
+
+		expected, err := LoadListItems(cmd.Param("file").String(), kind)
+		if err != nil {
+			return err
+		}
+
+		update, err := CompareListItemsForUpdate(kind, current, expected)
+		if err != nil {
+			return err
+		}
+
+		if list == nil {
+			create := ResourceItem{"name": name, "kind": kind}
+			if description := cmd.Flag("description").String(); description != "" {
+				create["description"] = description
+			}
+
+			err = cloudflare.UpdateResource(ListsResource, account, ResourceItemsForUpdate{{Key: name, Expected: create}}, logOnly)
+			if err == nil && !logOnly {
+				list, err = cloudflare.List(account, name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return cloudflare.UpdateListItems(account, name, resourceID(list), update, time.Second,
+			cmd.Flag("timeout").Get().(time.Duration), logOnly)
+	})
+}
+
+func customHostnamesStatus(cmd cli.Command) {
+	cloudflare := setup(cmd)
+	items, err := cloudflare.ResourceItems(CustomHostnamesResource, cmd.Param("zone_id").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := WriteCustomHostnameReport(os.Stdout, items); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func validate(cmd cli.Command) {
+	files := []string{cmd.Param("file").String()}
+	for _, arg := range cmd.Args() {
+		files = append(files, arg.String())
+	}
+
+	invalid := []string{}
+	for _, file := range files {
+		errs := ValidateConfigFile(file)
+		for _, err := range errs {
+			log.Println(err)
+		}
+		if errs.Invalid() {
+			invalid = append(invalid, file)
+		}
+	}
+
+	if len(invalid) > 0 {
+		log.Fatalln("Config is invalid:", strings.Join(invalid, ", "))
+	}
+	log.Println("Config is valid:", strings.Join(files, ", "))
+}
+
+func audit(cmd cli.Command) {
+	filter := AuditFilter{
+		Zone:  cmd.Flag("zone").String(),
+		Key:   cmd.Flag("setting").String(),
+		Since: getDateFlag(cmd, "since", false),
+		Until: getDateFlag(cmd, "until", true),
+	}
+
+	entries, err := LoadAuditEntries(cmd.Param("audit_file").String(), filter)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for _, entry := range entries {
+		outcome := fmt.Sprint(entry.Status)
+		if entry.Error != "" {
+			outcome = fmt.Sprintf("%d %s", entry.Status, entry.Error)
+		}
+
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\t%#v -> %#v\t%s\n",
+			entry.Time.Format(time.RFC3339), entry.Operator, entry.ZoneID, entry.ZoneName,
+			entry.Key, entry.Before, entry.After, outcome)
+	}
+}
+
+func policyCheck(cmd cli.Command) {
+	policy, err := LoadPolicy(cmd.Param("policy_file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	config, err := LoadConfigItems(cmd.Param("file").String())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if violations := policy.Check(config); len(violations) > 0 {
+		log.Fatalln(violations)
+	}
+
+	log.Println("Config satisfies policy:", len(policy.Rules), "rules checked")
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type CloudFlareAccountItem struct {
+	ID   string
+	Name string
+}
+
+func (c *CloudFlare) Account(accountID string) (CloudFlareAccountItem, error) {
+	var account CloudFlareAccountItem
+
+	req, err := c.Query.NewRequest("GET", fmt.Sprintf("/accounts/%s", accountID))
+	if err != nil {
+		return account, err
+	}
+
+	response, err := c.MakeRequest(req)
+	if err != nil {
+		return account, err
+	}
+
+	err = json.Unmarshal(response.Result, &account)
+
+	return account, err
+}
+
+// AccountRolesResource is the roles that can be given to an account's
+// members. Roles are referred to by name.
+var AccountRolesResource = ResourceType{
+	Name:    "role",
+	Path:    "/accounts/%s/roles",
+	Key:     accountRoleKey,
+	PerPage: 50,
+}
+
+func accountRoleKey(item ResourceItem) string {
+	name, _ := item["name"].(string)
+	return name
+}
+
+// AccountMembersResource manages the members of an account, in the shape of
+// the config file: each member's email address and the names of their
+// roles. Members are identified by email address.
+var AccountMembersResource = ResourceType{
+	Name:     "account member",
+	Path:     "/accounts/%s/members",
+	Key:      accountMemberKey,
+	PerPage:  50,
+	ReadOnly: []string{"id", "status"},
+	Update:   updateAccountMember,
+	Prepare:  prepareAccountMembers,
+}
+
+func accountMemberKey(item ResourceItem) string {
+	email, _ := item["email"].(string)
+	return email
+}
+
+// updateAccountMember changes a member's roles, which are sent as objects
+// rather than the list of IDs that members are created with.
+func updateAccountMember(c *CloudFlare, account string, current, item ResourceItem) error {
+	roles := []interface{}{}
+	if ids, ok := item["roles"].([]interface{}); ok {
+		for _, id := range ids {
+			roles = append(roles, map[string]interface{}{"id": id})
+		}
+	}
+
+	path := fmt.Sprintf("/accounts/%s/members/%s", account, resourceID(current))
+	_, err := c.sendResourceItem("PUT", path, map[string]interface{}{"roles": roles})
+
+	return err
+}
+
+// prepareAccountMembers returns copies of members with lower case email
+// addresses and sorted roles, so that neither the case of addresses nor the
+// order that roles are listed in is treated as a change.
+func prepareAccountMembers(items ResourceItems) (ResourceItems, error) {
+	prepared := ResourceItems{}
+	for _, item := range items {
+		copied := ResourceItem(copyResourceObject(item))
+		if email, ok := copied["email"].(string); ok {
+			copied["email"] = strings.ToLower(email)
+		}
+		if roles, ok := copied["roles"].([]interface{}); ok {
+			names := []string{}
+			for _, role := range roles {
+				if name, ok := role.(string); ok {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+
+			copied["roles"] = stringsToInterfaces(names)
+		}
+		prepared = append(prepared, copied)
+	}
+
+	return prepared, nil
+}
+
+func stringsToInterfaces(strs []string) []interface{} {
+	vals := []interface{}{}
+	for _, str := range strs {
+		vals = append(vals, str)
+	}
+
+	return vals
+}
+
+// AccountMembers returns an account's members in the shape of the config
+// file, with the IDs and statuses of the members.
+func (c *CloudFlare) AccountMembers(account string) (ResourceItems, error) {
+	members, err := c.ResourceItems(AccountMembersResource, account)
+	if err != nil {
+		return nil, err
+	}
+
+	config := ResourceItems{}
+	for _, member := range members {
+		item := ResourceItem{
+			"id":     member["id"],
+			"email":  "",
+			"roles":  []interface{}{},
+			"status": member["status"],
+		}
+		if user, ok := member["user"].(map[string]interface{}); ok {
+			item["email"] = user["email"]
+		}
+		if roles, ok := member["roles"].([]interface{}); ok {
+			names := []interface{}{}
+			for _, role := range roles {
+				if obj, ok := role.(map[string]interface{}); ok {
+					names = append(names, obj["name"])
+				}
+			}
+			item["roles"] = names
+		}
+
+		config = append(config, item)
+	}
+
+	return prepareAccountMembers(config)
+}
+
+// CompareAccountMembersForUpdate returns the changes to an account's
+// members. Expected members may only have roles that the account has.
+func CompareAccountMembersForUpdate(current, expected, roles ResourceItems) (ResourceItemsForUpdate, error) {
+	refs := newResourceRefs(AccountRolesResource.Name, roles, accountRoleKey)
+
+	expected, err := AccountMembersResource.Prepare(expected)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range expected {
+		if _, err := mapAccountMemberRoles(member, refs.checkName); err != nil {
+			return nil, err
+		}
+	}
+
+	return CompareResourceItemsForUpdate(AccountMembersResource, current, expected)
+}
+
+// mapAccountMemberRoles returns a copy of a member with its roles mapped.
+func mapAccountMemberRoles(member ResourceItem, mapping func(string) (string, error)) (ResourceItem, error) {
+	mapped := ResourceItem(copyResourceObject(member))
+
+	if roles, ok := mapped["roles"].([]interface{}); ok {
+		refs := []interface{}{}
+		for _, role := range roles {
+			name, _ := role.(string)
+			ref, err := mapping(name)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		}
+		mapped["roles"] = refs
+	}
+
+	return mapped, nil
+}
+
+// SkipAccountMemberRemovals returns the changes without the removal of
+// members that aren't in the config, logging each member that's left.
+func (c *CloudFlare) SkipAccountMemberRemovals(update ResourceItemsForUpdate) ResourceItemsForUpdate {
+	changes, removals := splitResourceDeletes(update)
+	for _, change := range removals {
+		c.log.Printf("Not removing %s %q, who isn't in config, without --prune", AccountMembersResource.Name, change.Key)
+	}
+
+	return changes
+}
+
+// UpdateAccountMembers invites new members, changes the roles of existing
+// members, and removes members. Roles are given to members by ID.
+func (c *CloudFlare) UpdateAccountMembers(account string, roles ResourceItems, update ResourceItemsForUpdate, logOnly bool) error {
+	refs := newResourceRefs(AccountRolesResource.Name, roles, accountRoleKey)
+
+	members := AccountMembersResource
+	members.Resolve = func(item ResourceItem) (ResourceItem, error) {
+		return mapAccountMemberRoles(item, refs.toID)
+	}
+
+	return c.UpdateResource(members, account, update, logOnly)
+}
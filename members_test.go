@@ -0,0 +1,159 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"log"
+	"net/http"
+)
+
+var _ = Describe("Members", func() {
+	var (
+		server     *ghttp.Server
+		logbuf     *gbytes.Buffer
+		cloudFlare *CloudFlare
+	)
+
+	respondWithItems := func(result string) http.HandlerFunc {
+		return ghttp.RespondWith(http.StatusOK, `{"errors": [], "messages": [], "success": true, "result": `+result+`}`)
+	}
+
+	roles := ResourceItems{
+		{"id": "r1", "name": "Administrator"},
+		{"id": "r2", "name": "DNS"},
+		{"id": "r3", "name": "Firewall"},
+	}
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		logbuf = gbytes.NewBuffer()
+		cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("AccountMembers()", func() {
+		It("should return members by email address with sorted role names", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/members", "page=1&per_page=50"),
+					respondWithItems(`[{
+						"id": "m1",
+						"user": {"id": "u1", "email": "alice@example.com"},
+						"status": "accepted",
+						"roles": [
+							{"id": "r3", "name": "Firewall"},
+							{"id": "r2", "name": "DNS"}
+						]
+					}]`),
+				),
+			)
+
+			members, err := cloudFlare.AccountMembers("abc")
+
+			Expect(err).To(BeNil())
+			Expect(members).To(Equal(ResourceItems{
+				{
+					"id":     "m1",
+					"email":  "alice@example.com",
+					"roles":  []interface{}{"DNS", "Firewall"},
+					"status": "accepted",
+				},
+			}))
+			Expect(AccountMembersResource.Strip(members)).To(Equal(ResourceItems{
+				{"email": "alice@example.com", "roles": []interface{}{"DNS", "Firewall"}},
+			}))
+		})
+	})
+
+	Describe("CompareAccountMembersForUpdate()", func() {
+		current := ResourceItems{
+			{"id": "m1", "email": "alice@example.com", "roles": []interface{}{"DNS", "Firewall"}, "status": "accepted"},
+			{"id": "m2", "email": "bob@example.com", "roles": []interface{}{"Administrator"}, "status": "accepted"},
+		}
+
+		It("should ignore the order of roles and the case of email addresses", func() {
+			update, err := CompareAccountMembersForUpdate(current, ResourceItems{
+				{"email": "Alice@example.com", "roles": []interface{}{"Firewall", "DNS"}},
+				{"email": "bob@example.com", "roles": []interface{}{"Administrator"}},
+			}, roles)
+
+			Expect(err).To(BeNil())
+			Expect(update).To(HaveLen(0))
+		})
+
+		It("should return an error for roles that the account doesn't have", func() {
+			_, err := CompareAccountMembersForUpdate(current, ResourceItems{
+				{"email": "alice@example.com", "roles": []interface{}{"Superuser"}},
+			}, roles)
+
+			Expect(err).To(MatchError(`Reference to unknown role "Superuser"`))
+		})
+
+		It("should not remove members without prune", func() {
+			update, err := CompareAccountMembersForUpdate(current, ResourceItems{
+				{"email": "alice@example.com", "roles": []interface{}{"DNS", "Firewall"}},
+			}, roles)
+
+			Expect(err).To(BeNil())
+			Expect(update).To(HaveLen(1))
+			Expect(cloudFlare.SkipAccountMemberRemovals(update)).To(HaveLen(0))
+			Expect(logbuf).To(gbytes.Say(`Not removing account member "bob@example.com", who isn't in config, without --prune`))
+		})
+	})
+
+	Describe("UpdateAccountMembers()", func() {
+		current := ResourceItems{
+			{"id": "m1", "email": "alice@example.com", "roles": []interface{}{"DNS"}, "status": "accepted"},
+			{"id": "m2", "email": "bob@example.com", "roles": []interface{}{"Administrator"}, "status": "accepted"},
+		}
+		expected := ResourceItems{
+			{"email": "alice@example.com", "roles": []interface{}{"DNS", "Firewall"}},
+			{"email": "carol@example.com", "roles": []interface{}{"Administrator"}},
+		}
+
+		It("should invite, change roles and remove members with role IDs", func() {
+			update, err := CompareAccountMembersForUpdate(current, expected, roles)
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/accounts/abc/members/m1"),
+					ghttp.VerifyJSON(`{"roles": [{"id": "r2"}, {"id": "r3"}]}`),
+					respondWithItems(`{"id": "m1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/accounts/abc/members"),
+					ghttp.VerifyJSON(`{"email": "carol@example.com", "roles": ["r1"]}`),
+					respondWithItems(`{"id": "m3"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/accounts/abc/members/m2"),
+					respondWithItems(`{"id": "m2"}`),
+				),
+			)
+
+			Expect(cloudFlare.UpdateAccountMembers("abc", roles, update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+		})
+
+		It("should log the plan with role names when logOnly is true", func() {
+			update, err := CompareAccountMembersForUpdate(current, expected, roles)
+			Expect(err).To(BeNil())
+
+			Expect(cloudFlare.UpdateAccountMembers("abc", roles, update, true)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+
+			Expect(logbuf).To(gbytes.Say(`Would have changed account member "alice@example.com" from {"roles":\["DNS"\]} to {"roles":\["DNS","Firewall"\]}`))
+			Expect(logbuf).To(gbytes.Say(`Would have created account member "carol@example.com": {"email":"carol@example.com","roles":\["Administrator"\]}`))
+			Expect(logbuf).To(gbytes.Say(`Would have deleted account member "bob@example.com"`))
+		})
+	})
+})
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// NotificationWebhooksResource manages an account's webhook destinations
+// for notifications, which are identified by their name.
+var NotificationWebhooksResource = ResourceType{
+	Name:         "webhook",
+	Path:         "/accounts/%s/alerting/v3/destinations/webhooks",
+	Key:          notificationNameKey,
+	ReadOnly:     []string{"id", "type", "created_at", "last_success", "last_failure"},
+	UpdateMethod: "PUT",
+	Describe:     describeNotificationWebhookChange,
+}
+
+// NotificationPoliciesResource manages an account's notification policies,
+// which send alerts of a type, that match their filters, to their
+// mechanisms. Policies are identified by their name.
+var NotificationPoliciesResource = ResourceType{
+	Name:         "notification policy",
+	Path:         "/accounts/%s/alerting/v3/policies",
+	Key:          notificationNameKey,
+	ReadOnly:     []string{"id", "created", "modified"},
+	UpdateMethod: "PUT",
+}
+
+func notificationNameKey(item ResourceItem) string {
+	name, _ := item["name"].(string)
+	return name
+}
+
+// describeNotificationWebhookChange describes webhooks that are only
+// changed so that their secrets are rotated.
+func describeNotificationWebhookChange(from, to ResourceItem) string {
+	if len(to) == 0 {
+		return "to rotate its secret"
+	}
+
+	return fmt.Sprintf("from %s to %s", jsonString(from), jsonString(to))
+}
+
+// notificationSecretField is the field of a webhook in config that names
+// the environment variable that its secret is read from, so that secrets
+// aren't kept in config. CloudFlare doesn't return secrets, so they're
+// only sent when a webhook is created or changed, or when secrets are
+// rotated.
+const notificationSecretField = "secret_env"
+
+// NotificationConfig is the webhooks and notification policies that are
+// managed together. Policies refer to webhooks by name instead of ID.
+type NotificationConfig struct {
+	Webhooks ResourceItems `json:"webhooks"`
+	Policies ResourceItems `json:"policies"`
+}
+
+// NotificationConfigForUpdate has the environment variables that each
+// webhook's secret is read from, by name, as well as the changes.
+type NotificationConfigForUpdate struct {
+	Webhooks ResourceItemsForUpdate
+	Policies ResourceItemsForUpdate
+	Secrets  map[string]string
+}
+
+// mapNotificationPolicyRefs returns a copy of a policy with its webhook
+// references mapped.
+func mapNotificationPolicyRefs(policy ResourceItem, mapping func(string) (string, error)) (ResourceItem, error) {
+	mapped := ResourceItem(copyResourceObject(policy))
+
+	mechanisms, _ := mapped["mechanisms"].(map[string]interface{})
+	webhooks, ok := mechanisms["webhooks"].([]interface{})
+	if !ok {
+		return mapped, nil
+	}
+
+	refs := []interface{}{}
+	for _, webhook := range webhooks {
+		if obj, ok := webhook.(map[string]interface{}); ok {
+			id, _ := obj["id"].(string)
+			ref, err := mapping(id)
+			if err != nil {
+				return nil, err
+			}
+
+			obj = copyResourceObject(obj)
+			obj["id"] = ref
+			webhook = obj
+		}
+		refs = append(refs, webhook)
+	}
+	mechanisms["webhooks"] = refs
+
+	return mapped, nil
+}
+
+// NotificationConfig returns an account's webhooks and notification
+// policies, with references by name.
+func (c *CloudFlare) NotificationConfig(account string) (NotificationConfig, error) {
+	var config NotificationConfig
+
+	webhooks, err := c.ResourceItems(NotificationWebhooksResource, account)
+	if err != nil {
+		return config, err
+	}
+	policies, err := c.ResourceItems(NotificationPoliciesResource, account)
+	if err != nil {
+		return config, err
+	}
+
+	webhookRefs := newResourceRefs(NotificationWebhooksResource.Name, webhooks, notificationNameKey)
+
+	config = NotificationConfig{Webhooks: webhooks, Policies: ResourceItems{}}
+	for _, policy := range policies {
+		mapped, _ := mapNotificationPolicyRefs(policy, webhookRefs.toName)
+		config.Policies = append(config.Policies, mapped)
+	}
+
+	return config, nil
+}
+
+// Strip returns a copy of the config without read-only fields.
+func (n NotificationConfig) Strip() NotificationConfig {
+	return NotificationConfig{
+		Webhooks: NotificationWebhooksResource.Strip(n.Webhooks),
+		Policies: NotificationPoliciesResource.Strip(n.Policies),
+	}
+}
+
+// CompareNotificationConfigForUpdate returns the changes to webhooks and
+// policies. Expected policies may only refer to expected webhooks. The
+// fields that name environment variables for secrets aren't compared, but
+// if rotateSecrets is true then every webhook that has a secret is changed
+// so that its secret is sent again.
+func CompareNotificationConfigForUpdate(current, expected NotificationConfig, rotateSecrets bool) (NotificationConfigForUpdate, error) {
+	update := NotificationConfigForUpdate{Secrets: map[string]string{}}
+
+	webhookNames := newResourceRefs(NotificationWebhooksResource.Name, expected.Webhooks, notificationNameKey)
+	for _, policy := range expected.Policies {
+		if _, err := mapNotificationPolicyRefs(policy, webhookNames.checkName); err != nil {
+			return update, err
+		}
+	}
+
+	webhooks := ResourceItems{}
+	for _, webhook := range expected.Webhooks {
+		webhook = ResourceItem(copyResourceObject(webhook))
+		if env, ok := webhook[notificationSecretField].(string); ok {
+			update.Secrets[notificationNameKey(webhook)] = env
+			delete(webhook, notificationSecretField)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	var err error
+	if update.Webhooks, err = CompareResourceItemsForUpdate(NotificationWebhooksResource, current.Webhooks, webhooks); err != nil {
+		return update, err
+	}
+	if rotateSecrets {
+		update.Webhooks = rotateNotificationWebhookSecrets(update.Webhooks, current.Webhooks, webhooks, update.Secrets)
+	}
+	if update.Policies, err = CompareResourceItemsForUpdate(NotificationPoliciesResource, current.Policies, expected.Policies); err != nil {
+		return update, err
+	}
+
+	return update, nil
+}
+
+// rotateNotificationWebhookSecrets adds changes for the current webhooks
+// that have secrets but wouldn't otherwise be changed.
+func rotateNotificationWebhookSecrets(update ResourceItemsForUpdate, current, expected ResourceItems, secrets map[string]string) ResourceItemsForUpdate {
+	changed := map[string]bool{}
+	for _, change := range update {
+		changed[change.Key] = true
+	}
+
+	currentByName := map[string]ResourceItem{}
+	for _, webhook := range current {
+		currentByName[notificationNameKey(webhook)] = webhook
+	}
+
+	for _, webhook := range expected {
+		name := notificationNameKey(webhook)
+		if _, ok := secrets[name]; ok && !changed[name] && currentByName[name] != nil {
+			update = append(update, ResourceItemForUpdate{Key: name, Current: currentByName[name], Expected: webhook})
+		}
+	}
+
+	return update
+}
+
+// notificationWebhookSecret reads the secret of a webhook from the
+// environment, if it has one.
+func notificationWebhookSecret(secrets map[string]string, name string) (string, bool, error) {
+	env, ok := secrets[name]
+	if !ok {
+		return "", false, nil
+	}
+
+	secret := os.Getenv(env)
+	if secret == "" {
+		return "", false, fmt.Errorf("Environment variable %s for secret of webhook %q isn't set", env, name)
+	}
+
+	return secret, true, nil
+}
+
+// UpdateNotificationConfig creates and updates webhooks, then makes
+// changes to policies, before deleting webhooks that are no longer used.
+// Secrets are read from the environment before any changes are made.
+func (c *CloudFlare) UpdateNotificationConfig(account string, update NotificationConfigForUpdate, logOnly bool) error {
+	webhookChanges, webhookDeletes := splitResourceDeletes(update.Webhooks)
+
+	webhooks := NotificationWebhooksResource
+	if !logOnly {
+		for _, change := range webhookChanges {
+			if _, _, err := notificationWebhookSecret(update.Secrets, change.Key); err != nil {
+				return err
+			}
+		}
+
+		webhooks.Resolve = func(item ResourceItem) (ResourceItem, error) {
+			secret, ok, err := notificationWebhookSecret(update.Secrets, notificationNameKey(item))
+			if ok {
+				item = ResourceItem(copyResourceObject(item))
+				item["secret"] = secret
+			}
+
+			return item, err
+		}
+	}
+	if err := c.UpdateResource(webhooks, account, webhookChanges, logOnly); err != nil {
+		return err
+	}
+
+	policies := NotificationPoliciesResource
+	if !logOnly {
+		current, err := c.ResourceItems(NotificationWebhooksResource, account)
+		if err != nil {
+			return err
+		}
+		webhookRefs := newResourceRefs(NotificationWebhooksResource.Name, current, notificationNameKey)
+		policies.Resolve = func(item ResourceItem) (ResourceItem, error) {
+			return mapNotificationPolicyRefs(item, webhookRefs.toID)
+		}
+	}
+	if err := c.UpdateResource(policies, account, update.Policies, logOnly); err != nil {
+		return err
+	}
+
+	return c.UpdateResource(NotificationWebhooksResource, account, webhookDeletes, logOnly)
+}
+
+func LoadNotificationConfig(file string) (NotificationConfig, error) {
+	var config NotificationConfig
+
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+
+	err = json.Unmarshal(bs, &config)
+
+	return config, err
+}
+
+func SaveNotificationConfig(config NotificationConfig, file string) error {
+	bs, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(file, bs, 0644)
+	return err
+}
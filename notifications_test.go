@@ -0,0 +1,221 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"log"
+	"net/http"
+	"os"
+)
+
+var _ = Describe("Notifications", func() {
+	var (
+		server     *ghttp.Server
+		logbuf     *gbytes.Buffer
+		cloudFlare *CloudFlare
+	)
+
+	respondWithItems := func(result string) http.HandlerFunc {
+		return ghttp.RespondWith(http.StatusOK, `{"errors": [], "messages": [], "success": true, "result": `+result+`}`)
+	}
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		logbuf = gbytes.NewBuffer()
+		cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.Unsetenv("ONCALL_WEBHOOK_SECRET")
+	})
+
+	Describe("NotificationConfig()", func() {
+		It("should replace references to webhook IDs with names", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/alerting/v3/destinations/webhooks"),
+					respondWithItems(`[{"id": "w1", "name": "oncall", "url": "https://oncall.example.com/hook", "type": "generic"}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/alerting/v3/policies"),
+					respondWithItems(`[{
+						"id": "p1",
+						"name": "DDoS",
+						"alert_type": "dos_attack_l7",
+						"enabled": true,
+						"mechanisms": {
+							"email": [{"id": "ops@example.com"}],
+							"webhooks": [{"id": "w1"}]
+						}
+					}]`),
+				),
+			)
+
+			config, err := cloudFlare.NotificationConfig("abc")
+
+			Expect(err).To(BeNil())
+			Expect(config.Strip()).To(Equal(NotificationConfig{
+				Webhooks: ResourceItems{
+					{"name": "oncall", "url": "https://oncall.example.com/hook"},
+				},
+				Policies: ResourceItems{
+					{
+						"name":       "DDoS",
+						"alert_type": "dos_attack_l7",
+						"enabled":    true,
+						"mechanisms": map[string]interface{}{
+							"email":    []interface{}{map[string]interface{}{"id": "ops@example.com"}},
+							"webhooks": []interface{}{map[string]interface{}{"id": "oncall"}},
+						},
+					},
+				},
+			}))
+		})
+	})
+
+	Describe("CompareNotificationConfigForUpdate()", func() {
+		It("should return an error for references to webhooks that aren't expected", func() {
+			_, err := CompareNotificationConfigForUpdate(NotificationConfig{}, NotificationConfig{
+				Policies: ResourceItems{
+					{
+						"name":       "DDoS",
+						"mechanisms": map[string]interface{}{"webhooks": []interface{}{map[string]interface{}{"id": "oncall"}}},
+					},
+				},
+			}, false)
+
+			Expect(err).To(MatchError(`Reference to unknown webhook "oncall"`))
+		})
+
+		It("should not compare the environment variables that secrets are read from", func() {
+			update, err := CompareNotificationConfigForUpdate(NotificationConfig{
+				Webhooks: ResourceItems{{"id": "w1", "name": "oncall", "url": "https://oncall.example.com/hook"}},
+			}, NotificationConfig{
+				Webhooks: ResourceItems{{"name": "oncall", "url": "https://oncall.example.com/hook", "secret_env": "ONCALL_WEBHOOK_SECRET"}},
+			}, false)
+
+			Expect(err).To(BeNil())
+			Expect(update.Webhooks).To(HaveLen(0))
+			Expect(update.Secrets).To(Equal(map[string]string{"oncall": "ONCALL_WEBHOOK_SECRET"}))
+		})
+
+		It("should change webhooks with secrets when they are rotated", func() {
+			update, err := CompareNotificationConfigForUpdate(NotificationConfig{
+				Webhooks: ResourceItems{
+					{"id": "w1", "name": "oncall", "url": "https://oncall.example.com/hook"},
+					{"id": "w2", "name": "chat", "url": "https://chat.example.com/hook"},
+				},
+			}, NotificationConfig{
+				Webhooks: ResourceItems{
+					{"name": "oncall", "url": "https://oncall.example.com/hook", "secret_env": "ONCALL_WEBHOOK_SECRET"},
+					{"name": "chat", "url": "https://chat.example.com/hook"},
+				},
+			}, true)
+
+			Expect(err).To(BeNil())
+			Expect(update.Webhooks).To(HaveLen(1))
+			Expect(update.Webhooks[0].Key).To(Equal("oncall"))
+			Expect(update.Webhooks[0].Action()).To(Equal("update"))
+		})
+	})
+
+	Describe("UpdateNotificationConfig()", func() {
+		expected := NotificationConfig{
+			Webhooks: ResourceItems{
+				{"name": "oncall", "url": "https://oncall.example.com/hook", "secret_env": "ONCALL_WEBHOOK_SECRET"},
+			},
+			Policies: ResourceItems{
+				{
+					"name":       "SSL expiry",
+					"alert_type": "universal_ssl_event_type",
+					"mechanisms": map[string]interface{}{"webhooks": []interface{}{map[string]interface{}{"id": "oncall"}}},
+				},
+			},
+		}
+
+		It("should create webhooks with secrets from the environment before policies that use them", func() {
+			os.Setenv("ONCALL_WEBHOOK_SECRET", "s3cr3t")
+
+			update, err := CompareNotificationConfigForUpdate(NotificationConfig{}, expected, false)
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/accounts/abc/alerting/v3/destinations/webhooks"),
+					ghttp.VerifyJSON(`{"name": "oncall", "url": "https://oncall.example.com/hook", "secret": "s3cr3t"}`),
+					respondWithItems(`{"id": "w1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/alerting/v3/destinations/webhooks"),
+					respondWithItems(`[{"id": "w1", "name": "oncall"}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/accounts/abc/alerting/v3/policies"),
+					ghttp.VerifyJSON(`{
+						"name": "SSL expiry",
+						"alert_type": "universal_ssl_event_type",
+						"mechanisms": {"webhooks": [{"id": "w1"}]}
+					}`),
+					respondWithItems(`{"id": "p1"}`),
+				),
+			)
+
+			Expect(cloudFlare.UpdateNotificationConfig("abc", update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+			Expect(logbuf).ToNot(gbytes.Say("s3cr3t"))
+		})
+
+		It("should return an error without making changes if a secret isn't set", func() {
+			update, err := CompareNotificationConfigForUpdate(NotificationConfig{}, expected, false)
+			Expect(err).To(BeNil())
+
+			err = cloudFlare.UpdateNotificationConfig("abc", update, false)
+
+			Expect(err).To(MatchError(`Environment variable ONCALL_WEBHOOK_SECRET for secret of webhook "oncall" isn't set`))
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+		})
+
+		It("should send the secret from the environment to rotate it", func() {
+			os.Setenv("ONCALL_WEBHOOK_SECRET", "n3w-s3cr3t")
+
+			update, err := CompareNotificationConfigForUpdate(NotificationConfig{
+				Webhooks: ResourceItems{{"id": "w1", "name": "oncall", "url": "https://oncall.example.com/hook", "type": "generic"}},
+			}, NotificationConfig{Webhooks: expected.Webhooks}, true)
+			Expect(err).To(BeNil())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/accounts/abc/alerting/v3/destinations/webhooks/w1"),
+					ghttp.VerifyJSON(`{"name": "oncall", "url": "https://oncall.example.com/hook", "secret": "n3w-s3cr3t"}`),
+					respondWithItems(`{"id": "w1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/alerting/v3/destinations/webhooks"),
+					respondWithItems(`[{"id": "w1", "name": "oncall"}]`),
+				),
+			)
+
+			Expect(cloudFlare.UpdateNotificationConfig("abc", update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+			Expect(logbuf).To(gbytes.Say(`Changing webhook "oncall" to rotate its secret`))
+			Expect(logbuf).ToNot(gbytes.Say("n3w-s3cr3t"))
+		})
+
+		It("should log changes without needing secrets when logOnly is true", func() {
+			update, err := CompareNotificationConfigForUpdate(NotificationConfig{}, expected, false)
+			Expect(err).To(BeNil())
+
+			Expect(cloudFlare.UpdateNotificationConfig("abc", update, true)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+
+			Expect(logbuf).To(gbytes.Say(`Would have created webhook "oncall": {"name":"oncall","url":"https://oncall.example.com/hook"}`))
+			Expect(logbuf).To(gbytes.Say(`Would have created notification policy "SSL expiry"`))
+		})
+	})
+})
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// OriginCAValidities are the number of days that CloudFlare will issue an
+// Origin CA certificate for.
+var OriginCAValidities = []int{7, 30, 90, 365, 730, 1095, 5475}
+
+// originCARequestTypes maps the key types that can be generated to the
+// request types that CloudFlare expect.
+var originCARequestTypes = map[string]string{
+	"rsa":   "origin-rsa",
+	"ecdsa": "origin-ecc",
+}
+
+// originCAHostname matches a DNS name, optionally with a wildcard as its
+// first label.
+var originCAHostname = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+type OriginCertificateRequest struct {
+	Hostnames         []string `json:"hostnames"`
+	RequestedValidity int      `json:"requested_validity"`
+	RequestType       string   `json:"request_type"`
+	CSR               string   `json:"csr"`
+}
+
+type OriginCertificate struct {
+	ID                string   `json:"id"`
+	Certificate       string   `json:"certificate"`
+	Hostnames         []string `json:"hostnames"`
+	ExpiresOn         string   `json:"expires_on"`
+	RequestType       string   `json:"request_type"`
+	RequestedValidity int      `json:"requested_validity"`
+}
+
+// NewOriginCertificateRequest generates a private key of keyType, which is
+// "rsa" or "ecdsa", and a CSR for hostnames signed by it. The key never
+// leaves this machine. It returns the request and PEM encoded key.
+func NewOriginCertificateRequest(keyType string, hostnames []string, validity int) (OriginCertificateRequest, []byte, error) {
+	var request OriginCertificateRequest
+
+	requestType, ok := originCARequestTypes[keyType]
+	if !ok {
+		return request, nil, fmt.Errorf("Unknown key type %q, must be rsa or ecdsa", keyType)
+	}
+	if len(hostnames) == 0 {
+		return request, nil, fmt.Errorf("At least one hostname is required")
+	}
+	for _, hostname := range hostnames {
+		if strings.HasPrefix(hostname, "-") {
+			return request, nil, fmt.Errorf("Flag %q must come before hostnames", hostname)
+		}
+		if len(hostname) > 253 || !originCAHostname.MatchString(hostname) {
+			return request, nil, fmt.Errorf("Invalid hostname %q", hostname)
+		}
+	}
+
+	validValidity := false
+	for _, days := range OriginCAValidities {
+		validValidity = validValidity || days == validity
+	}
+	if !validValidity {
+		return request, nil, fmt.Errorf("Invalid validity %d, must be one of: %v", validity, OriginCAValidities)
+	}
+
+	var (
+		signer crypto.Signer
+		keyPEM *pem.Block
+	)
+	switch keyType {
+	case "rsa":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return request, nil, err
+		}
+		signer = key
+		keyPEM = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	case "ecdsa":
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return request, nil, err
+		}
+		bs, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return request, nil, err
+		}
+		signer = key
+		keyPEM = &pem.Block{Type: "EC PRIVATE KEY", Bytes: bs}
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hostnames[0]},
+		DNSNames: hostnames,
+	}, signer)
+	if err != nil {
+		return request, nil, err
+	}
+
+	request = OriginCertificateRequest{
+		Hostnames:         hostnames,
+		RequestedValidity: validity,
+		RequestType:       requestType,
+		CSR:               string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})),
+	}
+
+	return request, pem.EncodeToMemory(keyPEM), nil
+}
+
+func (c *CloudFlare) CreateOriginCertificate(request OriginCertificateRequest) (OriginCertificate, error) {
+	var cert OriginCertificate
+
+	result, err := c.sendResourceItem("POST", "/certificates", request)
+	if err != nil {
+		return cert, err
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return cert, err
+	}
+	err = json.Unmarshal(bs, &cert)
+
+	return cert, err
+}
+
+func (c *CloudFlare) OriginCertificates(zone string) ([]OriginCertificate, error) {
+	var certs []OriginCertificate
+	err := c.allPages(fmt.Sprintf("/certificates?zone_id=%s", zone), &certs)
+
+	return certs, err
+}
+
+func (c *CloudFlare) RevokeOriginCertificate(id string) error {
+	_, err := c.sendResourceItem("DELETE", fmt.Sprintf("/certificates/%s", id), nil)
+	return err
+}
+
+// CheckFilesAbsent returns an error if any of the files exist, so that a
+// certificate isn't issued only to find that it can't be written.
+func CheckFilesAbsent(files ...string) error {
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			return fmt.Errorf("File already exists: %s", file)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteNewFile writes a file that must not already exist, so that existing
+// keys are never overwritten.
+func WriteNewFile(file string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,202 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var _ = Describe("OriginCA", func() {
+	Describe("NewOriginCertificateRequest()", func() {
+		parseCSR := func(request OriginCertificateRequest) *x509.CertificateRequest {
+			block, _ := pem.Decode([]byte(request.CSR))
+			Expect(block).ToNot(BeNil())
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			Expect(err).To(BeNil())
+
+			return csr
+		}
+
+		It("should generate an RSA key and CSR for the hostnames", func() {
+			request, key, err := NewOriginCertificateRequest("rsa", []string{"example.com", "*.example.com"}, 365)
+
+			Expect(err).To(BeNil())
+			Expect(string(key)).To(ContainSubstring("BEGIN RSA PRIVATE KEY"))
+			Expect(request.RequestType).To(Equal("origin-rsa"))
+			Expect(request.RequestedValidity).To(Equal(365))
+			Expect(request.Hostnames).To(Equal([]string{"example.com", "*.example.com"}))
+
+			csr := parseCSR(request)
+			Expect(csr.Subject.CommonName).To(Equal("example.com"))
+			Expect(csr.DNSNames).To(Equal([]string{"example.com", "*.example.com"}))
+			Expect(csr.CheckSignature()).To(BeNil())
+		})
+
+		It("should generate an ECDSA key and CSR", func() {
+			request, key, err := NewOriginCertificateRequest("ecdsa", []string{"example.com"}, 5475)
+
+			Expect(err).To(BeNil())
+			Expect(string(key)).To(ContainSubstring("BEGIN EC PRIVATE KEY"))
+			Expect(request.RequestType).To(Equal("origin-ecc"))
+			Expect(parseCSR(request).PublicKeyAlgorithm).To(Equal(x509.ECDSA))
+		})
+
+		It("should return an error for unknown key types and validities", func() {
+			_, _, err := NewOriginCertificateRequest("dsa", []string{"example.com"}, 365)
+			Expect(err).To(MatchError(`Unknown key type "dsa", must be rsa or ecdsa`))
+
+			_, _, err = NewOriginCertificateRequest("rsa", []string{"example.com"}, 100)
+			Expect(err).To(MatchError(ContainSubstring("Invalid validity 100")))
+		})
+
+		It("should return an error for flags given after hostnames", func() {
+			_, _, err := NewOriginCertificateRequest("rsa", []string{"example.com", "*.example.com", "--type", "ecdsa"}, 5475)
+			Expect(err).To(MatchError(`Flag "--type" must come before hostnames`))
+		})
+
+		It("should return an error for hostnames that aren't DNS names or wildcards", func() {
+			for _, hostname := range []string{"https://example.com", "www.*.example.com", "example..com", "exa mple.com"} {
+				_, _, err := NewOriginCertificateRequest("rsa", []string{hostname}, 5475)
+				Expect(err).To(MatchError(fmt.Sprintf("Invalid hostname %q", hostname)))
+			}
+		})
+	})
+
+	Describe("CloudFlare", func() {
+		var (
+			server     *ghttp.Server
+			cloudFlare *CloudFlare
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(gbytes.NewBuffer(), "", 0))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should issue a certificate", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/certificates"),
+					ghttp.VerifyJSON(`{
+						"hostnames": ["example.com"],
+						"requested_validity": 365,
+						"request_type": "origin-rsa",
+						"csr": "CSR"
+					}`),
+					ghttp.RespondWith(http.StatusOK, `{
+						"errors": [],
+						"messages": [],
+						"result": {
+							"id": "328578533902268680212849205732770752308931942346",
+							"certificate": "CERT",
+							"hostnames": ["example.com"],
+							"expires_on": "2015-10-17 14:00:00 +0000 UTC"
+						},
+						"success": true
+					}`),
+				),
+			)
+
+			cert, err := cloudFlare.CreateOriginCertificate(OriginCertificateRequest{
+				Hostnames:         []string{"example.com"},
+				RequestedValidity: 365,
+				RequestType:       "origin-rsa",
+				CSR:               "CSR",
+			})
+
+			Expect(err).To(BeNil())
+			Expect(cert.ID).To(Equal("328578533902268680212849205732770752308931942346"))
+			Expect(cert.Certificate).To(Equal("CERT"))
+		})
+
+		It("should list every page of a zone's certificates", func() {
+			certs := []string{}
+			for i := 0; i < CloudFlarePerPage; i++ {
+				certs = append(certs, fmt.Sprintf(`{"id": "%d", "hostnames": ["example.com"]}`, i))
+			}
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/certificates", fmt.Sprintf("zone_id=123&page=1&per_page=%d", CloudFlarePerPage)),
+					ghttp.RespondWith(http.StatusOK, fmt.Sprintf(`{"errors": [], "messages": [], "success": true, "result": [%s]}`, strings.Join(certs, ","))),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/certificates", fmt.Sprintf("zone_id=123&page=2&per_page=%d", CloudFlarePerPage)),
+					ghttp.RespondWith(http.StatusOK, `{
+						"errors": [],
+						"messages": [],
+						"result": [{"id": "last", "hostnames": ["example.com"]}],
+						"success": true
+					}`),
+				),
+			)
+
+			items, err := cloudFlare.OriginCertificates("123")
+
+			Expect(err).To(BeNil())
+			Expect(items).To(HaveLen(CloudFlarePerPage + 1))
+			Expect(items[CloudFlarePerPage]).To(Equal(OriginCertificate{ID: "last", Hostnames: []string{"example.com"}}))
+		})
+
+		It("should revoke a certificate", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/certificates/1"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			Expect(cloudFlare.RevokeOriginCertificate("1")).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+
+	Describe("WriteNewFile()", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "cloudflare-configure")
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("should write the file with the given permissions", func() {
+			file := filepath.Join(dir, "origin.key")
+			Expect(WriteNewFile(file, []byte("KEY"), 0600)).To(BeNil())
+
+			info, err := os.Stat(file)
+			Expect(err).To(BeNil())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+
+		It("should refuse to overwrite an existing file", func() {
+			file := filepath.Join(dir, "origin.key")
+			Expect(ioutil.WriteFile(file, []byte("OLD"), 0600)).To(BeNil())
+
+			Expect(CheckFilesAbsent(file)).To(MatchError("File already exists: " + file))
+			Expect(WriteNewFile(file, []byte("KEY"), 0600)).ToNot(BeNil())
+			Expect(ioutil.ReadFile(file)).To(Equal([]byte("OLD")))
+		})
+	})
+})
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PageRulesResource manages page rules. Rules are identified by the URL
+// patterns that they target.
+//
+// CloudFlare apply the rule with the highest priority when more than one
+// matches. Downloaded rules are saved from the highest priority to the
+// lowest, and local rules without a priority are given one from their
+// position, so that the order of a file is preserved. Files must give every
+// rule a priority or none of them, so that priorities aren't duplicated.
+var PageRulesResource = ResourceType{
+	Name:         "page rule",
+	Path:         "/zones/%s/pagerules",
+	Key:          pageRuleKey,
+	ReadOnly:     []string{"id", "created_on", "modified_on"},
+	UpdateMethod: "PUT",
+	Order:        orderPageRules,
+	Prepare:      preparePageRules,
+}
+
+func pageRuleKey(item ResourceItem) string {
+	values := []string{}
+
+	targets, _ := item["targets"].([]interface{})
+	for _, target := range targets {
+		target, _ := target.(map[string]interface{})
+		constraint, _ := target["constraint"].(map[string]interface{})
+		if value, ok := constraint["value"].(string); ok {
+			values = append(values, value)
+		}
+	}
+
+	return strings.Join(values, " ")
+}
+
+func pageRulePriority(item ResourceItem) float64 {
+	priority, _ := item["priority"].(float64)
+	return priority
+}
+
+type pageRulesByPriority ResourceItems
+
+func (p pageRulesByPriority) Len() int      { return len(p) }
+func (p pageRulesByPriority) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p pageRulesByPriority) Less(i, j int) bool {
+	return pageRulePriority(p[i]) > pageRulePriority(p[j])
+}
+
+func orderPageRules(items ResourceItems) ResourceItems {
+	sort.Stable(pageRulesByPriority(items))
+	return items
+}
+
+func preparePageRules(items ResourceItems) (ResourceItems, error) {
+	withPriority := 0
+	for _, item := range items {
+		if _, ok := item["priority"]; ok {
+			withPriority++
+		}
+	}
+
+	switch withPriority {
+	case len(items):
+		return items, nil
+	case 0:
+	default:
+		return nil, fmt.Errorf("%d of %d page rules have a priority, either all or none must have one",
+			withPriority, len(items))
+	}
+
+	for i, item := range items {
+		item["priority"] = float64(len(items) - i)
+	}
+
+	return items, nil
+}
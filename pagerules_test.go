@@ -0,0 +1,84 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PageRulesResource", func() {
+	pageRule := func(pattern string, priority float64) ResourceItem {
+		return ResourceItem{
+			"targets": []interface{}{
+				map[string]interface{}{
+					"target": "url",
+					"constraint": map[string]interface{}{
+						"operator": "matches",
+						"value":    pattern,
+					},
+				},
+			},
+			"actions": []interface{}{
+				map[string]interface{}{"id": "always_use_https"},
+			},
+			"priority": priority,
+			"status":   "active",
+		}
+	}
+
+	It("should key rules by their target URL patterns", func() {
+		Expect(PageRulesResource.Key(pageRule("http://*example.com/*", 1))).To(
+			Equal("http://*example.com/*"))
+	})
+
+	It("should order downloaded rules from highest to lowest priority", func() {
+		items := PageRulesResource.Order(ResourceItems{
+			pageRule("a", 1),
+			pageRule("b", 3),
+			pageRule("c", 2),
+		})
+
+		Expect(PageRulesResource.Key(items[0])).To(Equal("b"))
+		Expect(PageRulesResource.Key(items[1])).To(Equal("c"))
+		Expect(PageRulesResource.Key(items[2])).To(Equal("a"))
+	})
+
+	withoutPriority := func(pattern string) ResourceItem {
+		rule := pageRule(pattern, 0)
+		delete(rule, "priority")
+		return rule
+	}
+
+	It("should give local rules without a priority one from their position", func() {
+		items, err := PageRulesResource.Prepare(ResourceItems{
+			withoutPriority("a"),
+			withoutPriority("b"),
+			withoutPriority("c"),
+		})
+
+		Expect(err).To(BeNil())
+		Expect(items[0]["priority"]).To(Equal(float64(3)))
+		Expect(items[1]["priority"]).To(Equal(float64(2)))
+		Expect(items[2]["priority"]).To(Equal(float64(1)))
+	})
+
+	It("should keep the priorities of local rules that all have one", func() {
+		items, err := PageRulesResource.Prepare(ResourceItems{pageRule("a", 7), pageRule("b", 2)})
+
+		Expect(err).To(BeNil())
+		Expect(items[0]["priority"]).To(Equal(float64(7)))
+		Expect(items[1]["priority"]).To(Equal(float64(2)))
+	})
+
+	It("should reject local rules when only some of them have a priority", func() {
+		items, err := PageRulesResource.Prepare(ResourceItems{
+			withoutPriority("a"),
+			withoutPriority("b"),
+			pageRule("c", 2),
+		})
+
+		Expect(items).To(BeNil())
+		Expect(err).To(MatchError("1 of 3 page rules have a priority, either all or none must have one"))
+	})
+})
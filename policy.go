@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type PolicyRule struct {
+	ID      string        `json:"id"`
+	Key     string        `json:"key"`
+	Equals  interface{}   `json:"equals,omitempty"`
+	In      []interface{} `json:"in,omitempty"`
+	Min     *float64      `json:"min,omitempty"`
+	Max     *float64      `json:"max,omitempty"`
+	Message string        `json:"message,omitempty"`
+}
+
+type Policy struct {
+	Rules     []PolicyRule `json:"rules"`
+	Protected []string     `json:"protected,omitempty"`
+}
+
+type PolicyViolation struct {
+	RuleID  string
+	Key     string
+	Value   interface{}
+	Message string
+}
+
+type PolicyViolations []PolicyViolation
+
+func (p PolicyViolations) Error() string {
+	lines := []string{"Config violates policy:"}
+	for _, violation := range p {
+		lines = append(lines, fmt.Sprintf("  [%s] %s", violation.RuleID, violation.Message))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Check evaluates every rule against a complete desired config. A key that
+// the policy refers to but the config doesn't contain is a violation.
+func (p *Policy) Check(config ConfigItems) PolicyViolations {
+	violations := PolicyViolations{}
+	for _, rule := range p.Rules {
+		val, ok := config[rule.Key]
+		if !ok {
+			violations = append(violations, rule.violation(val,
+				fmt.Sprintf("setting %q is not present in config", rule.Key)))
+			continue
+		}
+
+		if reason := rule.evaluate(val); reason != "" {
+			violations = append(violations, rule.violation(val, reason))
+		}
+	}
+
+	return violations
+}
+
+// CheckUpdate evaluates every rule against the expected values of a set of
+// changes. Keys that aren't being changed are ignored.
+func (p *Policy) CheckUpdate(update ConfigItemsForUpdate) PolicyViolations {
+	violations := PolicyViolations{}
+	for _, rule := range p.Rules {
+		item, ok := update[rule.Key]
+		if !ok {
+			continue
+		}
+
+		if reason := rule.evaluate(item.Expected); reason != "" {
+			violations = append(violations, rule.violation(item.Expected, reason))
+		}
+	}
+
+	return violations
+}
+
+func (r PolicyRule) violation(val interface{}, reason string) PolicyViolation {
+	message := reason
+	if r.Message != "" {
+		message = fmt.Sprintf("%s (%s)", r.Message, reason)
+	}
+
+	return PolicyViolation{
+		RuleID:  r.ID,
+		Key:     r.Key,
+		Value:   val,
+		Message: message,
+	}
+}
+
+// evaluate returns the reason that a value fails the rule, or an empty
+// string if it passes.
+func (r PolicyRule) evaluate(val interface{}) string {
+	if r.Equals != nil && !reflect.DeepEqual(normalisePolicyValue(val), r.Equals) {
+		return fmt.Sprintf("setting %q is %#v, must be %#v", r.Key, val, r.Equals)
+	}
+
+	if len(r.In) > 0 {
+		found := false
+		for _, allowed := range r.In {
+			if reflect.DeepEqual(normalisePolicyValue(val), allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("setting %q is %#v, must be one of %#v", r.Key, val, r.In)
+		}
+	}
+
+	if r.Min != nil || r.Max != nil {
+		num, ok := policyNumber(val)
+		if !ok {
+			return fmt.Sprintf("setting %q is %#v, must be a number", r.Key, val)
+		}
+		if r.Min != nil && num < *r.Min {
+			return fmt.Sprintf("setting %q is %#v, must be at least %v", r.Key, val, *r.Min)
+		}
+		if r.Max != nil && num > *r.Max {
+			return fmt.Sprintf("setting %q is %#v, must be at most %v", r.Key, val, *r.Max)
+		}
+	}
+
+	return ""
+}
+
+// normalisePolicyValue converts integers, which may be present in configs
+// that weren't loaded from JSON, to the float64 that rules are decoded as.
+func normalisePolicyValue(val interface{}) interface{} {
+	if num, ok := val.(int); ok {
+		return float64(num)
+	}
+
+	return val
+}
+
+// policyNumber accepts numbers and numeric strings, because CloudFlare
+// represent some ordered settings such as min_tls_version as strings.
+func policyNumber(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		num, err := strconv.ParseFloat(v, 64)
+		return num, err == nil
+	}
+
+	return 0, false
+}
+
+func (p *Policy) validate() error {
+	seen := map[string]bool{}
+	for i, rule := range p.Rules {
+		if rule.ID == "" || rule.Key == "" {
+			return fmt.Errorf("Policy rule %d must have an id and key", i)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("Policy rule %q is defined more than once", rule.ID)
+		}
+		if rule.Equals == nil && len(rule.In) == 0 && rule.Min == nil && rule.Max == nil {
+			return fmt.Errorf("Policy rule %q has no conditions", rule.ID)
+		}
+		seen[rule.ID] = true
+	}
+
+	return nil
+}
+
+func LoadPolicy(file string) (*Policy, error) {
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(bs, &policy); err != nil {
+		return nil, err
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
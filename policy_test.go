@@ -0,0 +1,130 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Policy", func() {
+	minTLS := 1.2
+	policy := &Policy{
+		Rules: []PolicyRule{
+			{ID: "ssl-mode", Key: "ssl", In: []interface{}{"full", "strict"}},
+			{ID: "min-tls", Key: "min_tls_version", Min: &minTLS},
+			{ID: "https", Key: "always_use_https", Equals: "on", Message: "HTTPS is mandatory"},
+		},
+	}
+
+	Describe("Check()", func() {
+		It("should return no violations when config satisfies every rule", func() {
+			Expect(policy.Check(ConfigItems{
+				"ssl":              "strict",
+				"min_tls_version":  "1.2",
+				"always_use_https": "on",
+			})).To(BeEmpty())
+		})
+
+		It("should return a violation for each failing rule", func() {
+			violations := policy.Check(ConfigItems{
+				"ssl":              "flexible",
+				"min_tls_version":  "1.0",
+				"always_use_https": "off",
+			})
+
+			Expect(violations).To(HaveLen(3))
+			Expect(violations[0].RuleID).To(Equal("ssl-mode"))
+			Expect(violations[0].Message).To(Equal(
+				`setting "ssl" is "flexible", must be one of []interface {}{"full", "strict"}`))
+			Expect(violations[1].RuleID).To(Equal("min-tls"))
+			Expect(violations[1].Message).To(Equal(
+				`setting "min_tls_version" is "1.0", must be at least 1.2`))
+			Expect(violations[2].RuleID).To(Equal("https"))
+			Expect(violations[2].Message).To(Equal(
+				`HTTPS is mandatory (setting "always_use_https" is "off", must be "on")`))
+		})
+
+		It("should return a violation when a key is missing", func() {
+			violations := policy.Check(ConfigItems{
+				"ssl":             "full",
+				"min_tls_version": "1.3",
+			})
+
+			Expect(violations).To(HaveLen(1))
+			Expect(violations[0].RuleID).To(Equal("https"))
+			Expect(violations.Error()).To(ContainSubstring(`[https] HTTPS is mandatory`))
+		})
+	})
+
+	Describe("CheckUpdate()", func() {
+		It("should only evaluate keys that are being changed", func() {
+			violations := policy.CheckUpdate(ConfigItemsForUpdate{
+				"ssl": ConfigItemForUpdate{
+					Current:  "strict",
+					Expected: "off",
+				},
+				"browser_cache_ttl": ConfigItemForUpdate{
+					Current:  14400,
+					Expected: 7200,
+				},
+			})
+
+			Expect(violations).To(HaveLen(1))
+			Expect(violations[0].RuleID).To(Equal("ssl-mode"))
+			Expect(violations[0].Value).To(Equal("off"))
+		})
+	})
+
+	Describe("LoadPolicy()", func() {
+		var (
+			tempDir  string
+			tempFile string
+		)
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = ioutil.TempDir("", "cloudflare-configure")
+			Expect(err).To(BeNil())
+
+			tempFile = filepath.Join(tempDir, "policy.json")
+		})
+
+		AfterEach(func() {
+			err := os.RemoveAll(tempDir)
+			Expect(err).To(BeNil())
+		})
+
+		It("should read rules from a JSON file", func() {
+			err := ioutil.WriteFile(tempFile, []byte(`{
+				"rules": [
+					{"id": "min-tls", "key": "min_tls_version", "min": 1.2}
+				]
+			}`), 0644)
+			Expect(err).To(BeNil())
+
+			out, err := LoadPolicy(tempFile)
+			Expect(out).To(Equal(&Policy{
+				Rules: []PolicyRule{
+					{ID: "min-tls", Key: "min_tls_version", Min: &minTLS},
+				},
+			}))
+			Expect(err).To(BeNil())
+		})
+
+		It("should return an error for rules without conditions", func() {
+			err := ioutil.WriteFile(tempFile, []byte(`{
+				"rules": [{"id": "ssl-mode", "key": "ssl"}]
+			}`), 0644)
+			Expect(err).To(BeNil())
+
+			out, err := LoadPolicy(tempFile)
+			Expect(out).To(BeNil())
+			Expect(err).To(MatchError(`Policy rule "ssl-mode" has no conditions`))
+		})
+	})
+})
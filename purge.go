@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// PurgeBatchSize is the most files, tags, hosts or prefixes that CloudFlare
+// will purge in one request.
+const PurgeBatchSize = 30
+
+// PurgeRequest is what to purge from a zone's cache. Everything can't be
+// combined with the other fields.
+type PurgeRequest struct {
+	Everything bool
+	Files      []string
+	Tags       []string
+	Hosts      []string
+	Prefixes   []string
+}
+
+func (p PurgeRequest) Empty() bool {
+	return !p.Everything && len(p.Files) == 0 && len(p.Tags) == 0 && len(p.Hosts) == 0 && len(p.Prefixes) == 0
+}
+
+// Validate checks that every file is an absolute http or https URL. Flags
+// that are given after URLs aren't parsed, so they're reported as such
+// rather than being purged as files.
+func (p PurgeRequest) Validate() error {
+	for _, file := range p.Files {
+		if strings.HasPrefix(file, "-") {
+			return fmt.Errorf("Flag %q must come before URLs", file)
+		}
+
+		u, err := url.Parse(file)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%q isn't an absolute http or https URL", file)
+		}
+	}
+
+	return nil
+}
+
+// Purge purges a zone's cache, splitting lists into batches of no more than
+// PurgeBatchSize. Nothing is purged unless the request is valid.
+func (c *CloudFlare) Purge(zone string, purge PurgeRequest, logOnly bool) error {
+	if err := purge.Validate(); err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/zones/%s/purge_cache", zone)
+
+	if purge.Everything {
+		c.log.Printf("%s everything from cache", purgeAction(logOnly))
+		if logOnly {
+			return nil
+		}
+
+		_, err := c.sendResourceItem("POST", path, map[string]interface{}{"purge_everything": true})
+		return err
+	}
+
+	lists := []struct {
+		field string
+		name  string
+		items []string
+	}{
+		{"files", "files", purge.Files},
+		{"tags", "cache tags", purge.Tags},
+		{"hosts", "hosts", purge.Hosts},
+		{"prefixes", "prefixes", purge.Prefixes},
+	}
+
+	for _, list := range lists {
+		for start := 0; start < len(list.items); start += PurgeBatchSize {
+			end := start + PurgeBatchSize
+			if end > len(list.items) {
+				end = len(list.items)
+			}
+			batch := list.items[start:end]
+
+			c.log.Printf("%s %d %s from cache: %s", purgeAction(logOnly), len(batch), list.name,
+				strings.Join(batch, ", "))
+			if logOnly {
+				continue
+			}
+
+			if _, err := c.sendResourceItem("POST", path, map[string]interface{}{list.field: batch}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func purgeAction(logOnly bool) string {
+	if logOnly {
+		return "Would have purged"
+	}
+
+	return "Purging"
+}
+
+// ReadPurgeList reads one URL, tag, host or prefix per line from a file,
+// ignoring blank lines and lines starting with "#".
+func ReadPurgeList(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	items := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		items = append(items, line)
+	}
+
+	return items, scanner.Err()
+}
@@ -0,0 +1,139 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Purge", func() {
+	Describe("Purge()", func() {
+		var (
+			server     *ghttp.Server
+			logbuf     *gbytes.Buffer
+			cloudFlare *CloudFlare
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			logbuf = gbytes.NewBuffer()
+			cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should purge everything", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/purge_cache"),
+					ghttp.VerifyJSON(`{"purge_everything": true}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			Expect(cloudFlare.Purge("123", PurgeRequest{Everything: true}, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+			Expect(logbuf).To(gbytes.Say(`Purging everything from cache`))
+		})
+
+		It("should purge files in batches", func() {
+			files := []string{}
+			for i := 0; i < PurgeBatchSize+1; i++ {
+				files = append(files, fmt.Sprintf("https://example.com/%d", i))
+			}
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/purge_cache"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/purge_cache"),
+					ghttp.VerifyJSON(fmt.Sprintf(`{"files": ["https://example.com/%d"]}`, PurgeBatchSize)),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			Expect(cloudFlare.Purge("123", PurgeRequest{Files: files}, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+			Expect(logbuf).To(gbytes.Say(fmt.Sprintf(`Purging %d files from cache`, PurgeBatchSize)))
+			Expect(logbuf).To(gbytes.Say(`Purging 1 files from cache`))
+		})
+
+		It("should purge tags, hosts and prefixes", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyJSON(`{"tags": ["images"]}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyJSON(`{"hosts": ["www.example.com"]}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyJSON(`{"prefixes": ["www.example.com/blog"]}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			Expect(cloudFlare.Purge("123", PurgeRequest{
+				Tags:     []string{"images"},
+				Hosts:    []string{"www.example.com"},
+				Prefixes: []string{"www.example.com/blog"},
+			}, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+		})
+
+		It("should log without purging when logOnly is true", func() {
+			Expect(cloudFlare.Purge("123", PurgeRequest{Everything: true}, true)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+			Expect(logbuf).To(gbytes.Say(`Would have purged everything from cache`))
+		})
+
+		It("should not purge anything if a flag was given after a URL", func() {
+			err := cloudFlare.Purge("123", PurgeRequest{
+				Files: []string{"https://www.example.com/", "--files-from", "urls.txt", "--dry-run"},
+			}, false)
+
+			Expect(err).To(MatchError(`Flag "--files-from" must come before URLs`))
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+		})
+
+		It("should not purge anything if a file isn't an absolute http or https URL", func() {
+			err := cloudFlare.Purge("123", PurgeRequest{
+				Files: []string{"https://www.example.com/", "www.example.com/about"},
+			}, false)
+
+			Expect(err).To(MatchError(`"www.example.com/about" isn't an absolute http or https URL`))
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+		})
+	})
+
+	Describe("ReadPurgeList()", func() {
+		It("should ignore blank lines and comments", func() {
+			dir, err := ioutil.TempDir("", "cloudflare-configure")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "urls.txt")
+			Expect(ioutil.WriteFile(file, []byte("# images\nhttps://example.com/a.png\n\n  https://example.com/b.png  \n"), 0644)).To(BeNil())
+
+			Expect(ReadPurgeList(file)).To(Equal([]string{
+				"https://example.com/a.png",
+				"https://example.com/b.png",
+			}))
+		})
+	})
+})
@@ -0,0 +1,26 @@
+package main
+
+// RateLimitsResource manages a zone's rate limiting rules, which take an
+// action for a period once a client has made more than a threshold of
+// matching requests. Rules are identified by their description, or by the
+// URL pattern that they match if they don't have one.
+var RateLimitsResource = ResourceType{
+	Name:         "rate limit",
+	Path:         "/zones/%s/rate_limits",
+	Key:          rateLimitKey,
+	PerPage:      100,
+	ReadOnly:     []string{"id"},
+	UpdateMethod: "PUT",
+}
+
+func rateLimitKey(item ResourceItem) string {
+	if description, ok := item["description"].(string); ok && description != "" {
+		return description
+	}
+
+	match, _ := item["match"].(map[string]interface{})
+	request, _ := match["request"].(map[string]interface{})
+	url, _ := request["url"].(string)
+
+	return url
+}
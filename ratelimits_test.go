@@ -0,0 +1,94 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"log"
+	"net/http"
+)
+
+var _ = Describe("RateLimitsResource", func() {
+	rateLimit := func(description string) ResourceItem {
+		return ResourceItem{
+			"description": description,
+			"match": map[string]interface{}{
+				"request": map[string]interface{}{
+					"methods": []interface{}{"POST"},
+					"url":     "*.example.com/login",
+				},
+			},
+			"threshold": float64(10),
+			"period":    float64(60),
+			"action": map[string]interface{}{
+				"mode":    "ban",
+				"timeout": float64(600),
+			},
+		}
+	}
+
+	It("should key rules by their description", func() {
+		Expect(RateLimitsResource.Key(rateLimit("Login attempts"))).To(Equal("Login attempts"))
+	})
+
+	It("should key rules without a description by their URL pattern", func() {
+		Expect(RateLimitsResource.Key(rateLimit(""))).To(Equal("*.example.com/login"))
+	})
+
+	It("should only compare the fields that are given", func() {
+		current := rateLimit("Login attempts")
+		current["id"] = "372e67954025e0ba6aaa6d586b9e0b59"
+
+		update, err := CompareResourceItemsForUpdate(RateLimitsResource, ResourceItems{current}, ResourceItems{
+			{
+				"description": "Login attempts",
+				"threshold":   float64(5),
+				"action":      map[string]interface{}{"timeout": float64(600)},
+			},
+		})
+
+		Expect(err).To(BeNil())
+		Expect(update).To(HaveLen(1))
+		from, to := update[0].ChangedFields()
+		Expect(from).To(Equal(ResourceItem{"threshold": float64(10)}))
+		Expect(to).To(Equal(ResourceItem{"threshold": float64(5)}))
+	})
+	It("should keep the current fields of the action that aren't given when updating", func() {
+		server := ghttp.NewServer()
+		defer server.Close()
+		cloudFlare := NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(gbytes.NewBuffer(), "", 0))
+
+		current := rateLimit("Login attempts")
+		current["id"] = "372e67954025e0ba6aaa6d586b9e0b59"
+
+		update, err := CompareResourceItemsForUpdate(RateLimitsResource, ResourceItems{current}, ResourceItems{
+			{
+				"description": "Login attempts",
+				"threshold":   float64(5),
+				"action":      map[string]interface{}{"timeout": float64(600)},
+			},
+		})
+		Expect(err).To(BeNil())
+
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/zones/123/rate_limits/372e67954025e0ba6aaa6d586b9e0b59"),
+				ghttp.VerifyJSON(`{
+					"description": "Login attempts",
+					"match": {"request": {"methods": ["POST"], "url": "*.example.com/login"}},
+					"threshold": 5,
+					"period": 60,
+					"action": {"mode": "ban", "timeout": 600}
+				}`),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+			),
+		)
+
+		Expect(cloudFlare.UpdateResource(RateLimitsResource, "123", update, false)).To(BeNil())
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+})
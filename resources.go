@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// ResourceItem is one object, such as a page rule, from a collection that
+// can be listed, created, updated and deleted through the API.
+type ResourceItem map[string]interface{}
+
+type ResourceItems []ResourceItem
+
+// ResourceType describes a collection of resources: where its endpoints are
+// and how items are matched between the local and remote collections.
+type ResourceType struct {
+	// Name is used in log messages, eg. "page rule".
+	Name string
+	// Path of the collection, relative to the API root, with %s in place of
+	// the zone ID, or the account ID for resources that belong to accounts.
+	Path string
+	// Key returns a string that uniquely identifies an item in a collection.
+	Key func(ResourceItem) string
+	// Keys, if set, is used instead of Key when comparing collections, for
+	// resources where the way that an item is identified depends on the
+	// other items in both collections.
+	Keys func(current, expected ResourceItems) func(ResourceItem) string
+	// PerPage is the number of items to request per page from collections
+	// that are paginated. Zero means that the collection isn't paginated.
+	PerPage int
+	// Filter, if set, excludes remote items that aren't managed, such as
+	// those inherited from an account.
+	Filter func(ResourceItem) bool
+	// ReadOnly fields are set by CloudFlare and aren't saved when downloading.
+	// Fields of objects are given as dotted paths, eg. "filter.id".
+	ReadOnly []string
+	// CreateOnly fields can't be changed after an item has been created and
+	// are removed from updates.
+	CreateOnly []string
+	// Fixed is true for collections whose items can't be created or deleted,
+	// only updated. Items that aren't expected are left alone.
+	Fixed bool
+	// CreateInList is true if the create endpoint takes a list of items.
+	CreateInList bool
+	// UpdateMethod is the HTTP method used to update an item.
+	UpdateMethod string
+	// Update, if set, replaces the request that is made to update an item,
+	// for resources that need more than one request.
+	Update func(c *CloudFlare, zone string, current, item ResourceItem) error
+	// Describe, if set, replaces the changed fields in log messages for
+	// updates, for resources whose changes are hard to read as JSON.
+	Describe func(from, to ResourceItem) string
+	// Resolve, if set, converts an item before it's created or updated, eg.
+	// replacing the names of other resources that it refers to with IDs.
+	Resolve func(ResourceItem) (ResourceItem, error)
+	// DeleteParams, if set, is a query string added to delete requests.
+	DeleteParams string
+	// Order, if set, sorts downloaded items before they are saved.
+	Order func(ResourceItems) ResourceItems
+	// Prepare, if set, is applied to local items before they are compared,
+	// and may reject them.
+	Prepare func(ResourceItems) (ResourceItems, error)
+}
+
+func (r ResourceType) CollectionPath(zone string) string {
+	return fmt.Sprintf(r.Path, zone)
+}
+
+func (r ResourceType) ItemPath(zone, id string) string {
+	return fmt.Sprintf("%s/%s", r.CollectionPath(zone), id)
+}
+
+// Strip returns copies of items without their read-only fields.
+func (r ResourceType) Strip(items ResourceItems) ResourceItems {
+	stripped := ResourceItems{}
+	for _, item := range items {
+		copied := copyResourceObject(item)
+		for _, path := range r.ReadOnly {
+			deleteResourceField(copied, strings.Split(path, "."))
+		}
+		stripped = append(stripped, copied)
+	}
+
+	return stripped
+}
+
+// copyResourceObject copies an object and any objects nested within it, so
+// that fields can be deleted without modifying the original.
+func copyResourceObject(obj map[string]interface{}) map[string]interface{} {
+	copied := map[string]interface{}{}
+	for key, val := range obj {
+		if nested, ok := val.(map[string]interface{}); ok {
+			val = copyResourceObject(nested)
+		}
+		copied[key] = val
+	}
+
+	return copied
+}
+
+// mergeResourceObject overlays the fields of expected onto obj. Objects that
+// are nested in both are merged, so that their fields that aren't expected
+// keep their current values, in the same way that they're compared.
+func mergeResourceObject(obj, expected map[string]interface{}) {
+	for key, val := range expected {
+		nested, ok := val.(map[string]interface{})
+		if current, isObj := obj[key].(map[string]interface{}); ok && isObj {
+			mergeResourceObject(current, nested)
+			continue
+		}
+
+		if ok {
+			val = copyResourceObject(nested)
+		}
+		obj[key] = val
+	}
+}
+
+func deleteResourceField(obj map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+
+	if nested, ok := obj[path[0]].(map[string]interface{}); ok {
+		deleteResourceField(nested, path[1:])
+	}
+}
+
+type ResourceKeyDuplicated struct {
+	Name string
+	Key  string
+}
+
+func (r ResourceKeyDuplicated) Error() string {
+	return fmt.Sprintf("More than one %s with the key %q", r.Name, r.Key)
+}
+
+type ResourceItemUnknown struct {
+	Name string
+	Key  string
+}
+
+func (r ResourceItemUnknown) Error() string {
+	return fmt.Sprintf("Unknown %s %q", r.Name, r.Key)
+}
+
+// ResourceItemForUpdate is a change to one item. Current is nil for items
+// that will be created and Expected is nil for items that will be deleted.
+type ResourceItemForUpdate struct {
+	Key      string
+	Current  ResourceItem
+	Expected ResourceItem
+}
+
+func (r ResourceItemForUpdate) Action() string {
+	switch {
+	case r.Current == nil:
+		return "create"
+	case r.Expected == nil:
+		return "delete"
+	}
+
+	return "update"
+}
+
+// ChangedFields returns the expected fields whose values differ from the
+// current item, and the current values of those fields.
+func (r ResourceItemForUpdate) ChangedFields() (ResourceItem, ResourceItem) {
+	from, to := ResourceItem{}, ResourceItem{}
+	for key, val := range r.Expected {
+		if !resourceValueMatches(r.Current[key], val) {
+			from[key] = r.Current[key]
+			to[key] = val
+		}
+	}
+
+	return from, to
+}
+
+// resourceValueMatches compares values in the same way as items: objects
+// match if the fields present in the expected object match.
+func resourceValueMatches(current, expected interface{}) bool {
+	expectedObj, ok := expected.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(current, expected)
+	}
+
+	currentObj, ok := current.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for key, val := range expectedObj {
+		if !resourceValueMatches(currentObj[key], val) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type ResourceItemsForUpdate []ResourceItemForUpdate
+
+// CompareResourceItemsForUpdate returns the changes needed to make current
+// match expected: creates and updates in the order of expected, followed by
+// deletes. Only the fields present in an expected item are compared, so
+// fields that are omitted locally aren't managed.
+func CompareResourceItemsForUpdate(resource ResourceType, current, expected ResourceItems) (ResourceItemsForUpdate, error) {
+	keyFunc := resource.Key
+	if resource.Keys != nil {
+		keyFunc = resource.Keys(current, expected)
+	}
+
+	currentByKey := map[string]ResourceItem{}
+	for _, item := range current {
+		key := keyFunc(item)
+		if _, ok := currentByKey[key]; ok {
+			return nil, ResourceKeyDuplicated{Name: resource.Name, Key: key}
+		}
+		currentByKey[key] = item
+	}
+
+	update := ResourceItemsForUpdate{}
+	expectedKeys := map[string]bool{}
+	for _, item := range expected {
+		key := keyFunc(item)
+		if expectedKeys[key] {
+			return nil, ResourceKeyDuplicated{Name: resource.Name, Key: key}
+		}
+		expectedKeys[key] = true
+
+		change := ResourceItemForUpdate{
+			Key:      key,
+			Current:  currentByKey[key],
+			Expected: item,
+		}
+		if resource.Fixed && change.Current == nil {
+			return nil, ResourceItemUnknown{Name: resource.Name, Key: key}
+		}
+		if from, _ := change.ChangedFields(); change.Current == nil || len(from) > 0 {
+			update = append(update, change)
+		}
+	}
+
+	if resource.Fixed {
+		return update, nil
+	}
+
+	for _, item := range current {
+		if key := keyFunc(item); !expectedKeys[key] {
+			update = append(update, ResourceItemForUpdate{
+				Key:     key,
+				Current: item,
+			})
+		}
+	}
+
+	return update, nil
+}
+
+// ResourceItems returns every item in a collection. Paginated collections
+// are requested one page at a time until a page isn't full.
+func (c *CloudFlare) ResourceItems(resource ResourceType, zone string) (ResourceItems, error) {
+	items := ResourceItems{}
+	path := resource.CollectionPath(zone)
+
+	for page := 1; ; page++ {
+		pagePath := path
+		if resource.PerPage > 0 {
+			separator := "?"
+			if strings.Contains(path, "?") {
+				separator = "&"
+			}
+			pagePath = fmt.Sprintf("%s%spage=%d&per_page=%d", path, separator, page, resource.PerPage)
+		}
+
+		req, err := c.Query.NewRequest("GET", pagePath)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := c.MakeRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageItems ResourceItems
+		if err := json.Unmarshal(response.Result, &pageItems); err != nil {
+			return nil, err
+		}
+		for _, item := range pageItems {
+			if resource.Filter == nil || resource.Filter(item) {
+				items = append(items, item)
+			}
+		}
+
+		if resource.PerPage == 0 || len(pageItems) < resource.PerPage {
+			return items, nil
+		}
+	}
+}
+
+func (c *CloudFlare) sendResourceItem(method, path string, item interface{}) (ResourceItem, error) {
+	var result ResourceItem
+
+	var body *bytes.Buffer
+	if item != nil {
+		bs, err := json.Marshal(item)
+		if err != nil {
+			return result, err
+		}
+		body = bytes.NewBuffer(bs)
+	} else {
+		body = &bytes.Buffer{}
+	}
+
+	req, err := c.Query.NewRequestBody(method, path, body)
+	if err != nil {
+		return result, err
+	}
+
+	response, err := c.MakeRequest(req)
+	if err != nil {
+		return result, err
+	}
+
+	if len(response.Result) > 0 && response.Result[0] == '{' {
+		err = json.Unmarshal(response.Result, &result)
+	}
+
+	return result, err
+}
+
+func (c *CloudFlare) CreateResourceItem(resource ResourceType, zone string, item ResourceItem) (ResourceItem, error) {
+	if resource.CreateInList {
+		return c.sendResourceItem("POST", resource.CollectionPath(zone), ResourceItems{item})
+	}
+
+	return c.sendResourceItem("POST", resource.CollectionPath(zone), item)
+}
+
+func (c *CloudFlare) UpdateResourceItem(resource ResourceType, zone, id string, item ResourceItem) (ResourceItem, error) {
+	return c.sendResourceItem(resource.UpdateMethod, resource.ItemPath(zone, id), item)
+}
+
+func (c *CloudFlare) DeleteResourceItem(resource ResourceType, zone, id string) error {
+	path := resource.ItemPath(zone, id)
+	if resource.DeleteParams != "" {
+		path = fmt.Sprintf("%s?%s", path, resource.DeleteParams)
+	}
+
+	_, err := c.sendResourceItem("DELETE", path, nil)
+	return err
+}
+
+// UpdateResource applies changes to a collection, or only logs them if
+// logOnly is true. Items are updated with their current fields, less those
+// that are read-only, merged with the expected fields.
+func (c *CloudFlare) UpdateResource(resource ResourceType, zone string, update ResourceItemsForUpdate, logOnly bool) error {
+	for _, change := range update {
+		var err error
+
+		switch change.Action() {
+		case "create":
+			c.log.Printf("%s %s %q: %s", resourceAction("create", logOnly), resource.Name,
+				change.Key, jsonString(change.Expected))
+			if !logOnly {
+				item := change.Expected
+				if resource.Resolve != nil {
+					item, err = resource.Resolve(item)
+				}
+				if err == nil {
+					_, err = c.CreateResourceItem(resource, zone, item)
+				}
+			}
+		case "update":
+			from, to := change.ChangedFields()
+			description := fmt.Sprintf("from %s to %s", jsonString(from), jsonString(to))
+			if resource.Describe != nil {
+				description = resource.Describe(from, to)
+			}
+			c.log.Printf("%s %s %q %s", resourceAction("update", logOnly), resource.Name,
+				change.Key, description)
+			if !logOnly {
+				item := resource.Strip(ResourceItems{change.Current})[0]
+				mergeResourceObject(item, change.Expected)
+				for _, key := range resource.CreateOnly {
+					delete(item, key)
+				}
+
+				if resource.Resolve != nil {
+					item, err = resource.Resolve(item)
+				}
+
+				switch {
+				case err != nil:
+				case resource.Update != nil:
+					err = resource.Update(c, zone, change.Current, item)
+				default:
+					_, err = c.UpdateResourceItem(resource, zone, resourceID(change.Current), item)
+				}
+			}
+		case "delete":
+			c.log.Printf("%s %s %q", resourceAction("delete", logOnly), resource.Name, change.Key)
+			if !logOnly {
+				err = c.DeleteResourceItem(resource, zone, resourceID(change.Current))
+			}
+		}
+
+		if !logOnly {
+			key := fmt.Sprintf("%s %s", resource.Name, change.Key)
+			if err = c.audit(zone, key, resourceAuditValue(change.Current), resourceAuditValue(change.Expected), err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resourceActions are the log messages for each action, when it is being
+// made and when logOnly is true.
+var resourceActions = map[string][2]string{
+	"create": {"Creating", "Would have created"},
+	"update": {"Changing", "Would have changed"},
+	"delete": {"Deleting", "Would have deleted"},
+	"add":    {"Adding", "Would have added"},
+	"move":   {"Moving", "Would have moved"},
+	"remove": {"Removing", "Would have removed"},
+}
+
+func resourceAction(action string, logOnly bool) string {
+	if logOnly {
+		return resourceActions[action][1]
+	}
+
+	return resourceActions[action][0]
+}
+
+func resourceID(item ResourceItem) string {
+	id, _ := item["id"].(string)
+	return id
+}
+
+// resourceString returns a string from an object nested within an
+// item, eg. resourceString(item, "ssl", "status").
+func resourceString(obj map[string]interface{}, path ...string) string {
+	for _, field := range path[:len(path)-1] {
+		obj, _ = obj[field].(map[string]interface{})
+	}
+
+	val, _ := obj[path[len(path)-1]].(string)
+	return val
+}
+
+// resourceAuditValue avoids recording a nil ResourceItem as an empty map.
+func resourceAuditValue(item ResourceItem) interface{} {
+	if item == nil {
+		return nil
+	}
+
+	return item
+}
+
+// resourceRefs maps between the names and IDs of items that other resources
+// refer to. Unknown IDs are left alone, because they may belong to items
+// that aren't managed, but an unknown name is an error.
+type resourceRefs struct {
+	name  string
+	byID  map[string]string
+	names map[string]bool
+}
+
+func newResourceRefs(name string, items ResourceItems, key func(ResourceItem) string) resourceRefs {
+	refs := resourceRefs{name: name, byID: map[string]string{}, names: map[string]bool{}}
+	for _, item := range items {
+		refs.byID[resourceID(item)] = key(item)
+		refs.names[key(item)] = true
+	}
+
+	return refs
+}
+
+func (l resourceRefs) toName(id string) (string, error) {
+	if name, ok := l.byID[id]; ok {
+		return name, nil
+	}
+
+	return id, nil
+}
+
+func (l resourceRefs) checkName(name string) (string, error) {
+	if !l.names[name] {
+		return "", fmt.Errorf("Reference to unknown %s %q", l.name, name)
+	}
+
+	return name, nil
+}
+
+func (l resourceRefs) toID(name string) (string, error) {
+	for id, idName := range l.byID {
+		if idName == name {
+			return id, nil
+		}
+	}
+
+	return "", fmt.Errorf("Reference to unknown %s %q", l.name, name)
+}
+
+func LoadResourceItems(file string) (ResourceItems, error) {
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var items ResourceItems
+	err = json.Unmarshal(bs, &items)
+
+	return items, err
+}
+
+func SaveResourceItems(items ResourceItems, file string) error {
+	bs, err := json.MarshalIndent(items, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(file, bs, 0644)
+	return err
+}
@@ -0,0 +1,235 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"log"
+	"net/http"
+)
+
+var _ = Describe("Resources", func() {
+	resource := ResourceType{
+		Name:         "widget",
+		Path:         "/zones/%s/widgets",
+		Key:          func(item ResourceItem) string { return item["name"].(string) },
+		ReadOnly:     []string{"id", "modified_on"},
+		UpdateMethod: "PATCH",
+	}
+
+	Describe("Strip()", func() {
+		It("should remove read-only fields without modifying the original", func() {
+			items := ResourceItems{
+				{"id": "1", "name": "foo", "modified_on": "2014-07-09T11:50:56.595672Z"},
+			}
+
+			Expect(resource.Strip(items)).To(Equal(ResourceItems{
+				{"name": "foo"},
+			}))
+			Expect(items[0]).To(HaveKey("id"))
+		})
+	})
+
+	Describe("CompareResourceItemsForUpdate()", func() {
+		current := ResourceItems{
+			{"id": "1", "name": "foo", "size": float64(1), "colour": "red"},
+			{"id": "2", "name": "bar", "size": float64(2)},
+			{"id": "3", "name": "baz", "size": float64(3)},
+		}
+
+		It("should return nothing when the expected fields match", func() {
+			update, err := CompareResourceItemsForUpdate(resource, current, ResourceItems{
+				{"name": "foo", "size": float64(1)},
+				{"name": "bar", "size": float64(2)},
+				{"name": "baz"},
+			})
+
+			Expect(update).To(Equal(ResourceItemsForUpdate{}))
+			Expect(err).To(BeNil())
+		})
+
+		It("should return creates and updates in order followed by deletes", func() {
+			update, err := CompareResourceItemsForUpdate(resource, current, ResourceItems{
+				{"name": "qux", "size": float64(4)},
+				{"name": "foo", "size": float64(10)},
+				{"name": "bar", "size": float64(2)},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update).To(HaveLen(3))
+
+			Expect(update[0].Action()).To(Equal("create"))
+			Expect(update[0].Key).To(Equal("qux"))
+
+			Expect(update[1].Action()).To(Equal("update"))
+			Expect(update[1].Key).To(Equal("foo"))
+			from, to := update[1].ChangedFields()
+			Expect(from).To(Equal(ResourceItem{"size": float64(1)}))
+			Expect(to).To(Equal(ResourceItem{"size": float64(10)}))
+
+			Expect(update[2].Action()).To(Equal("delete"))
+			Expect(update[2].Key).To(Equal("baz"))
+		})
+
+		It("should return an error when keys are duplicated", func() {
+			update, err := CompareResourceItemsForUpdate(resource, current, ResourceItems{
+				{"name": "foo"},
+				{"name": "foo"},
+			})
+
+			Expect(update).To(BeNil())
+			Expect(err).To(MatchError(`More than one widget with the key "foo"`))
+		})
+	})
+
+	Describe("ResourceItems()", func() {
+		var (
+			server     *ghttp.Server
+			cloudFlare *CloudFlare
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(gbytes.NewBuffer(), "", 0))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should request pages until one isn't full", func() {
+			paginated := resource
+			paginated.PerPage = 2
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/zones/123/widgets", "page=1&per_page=2"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"errors": [],
+						"messages": [],
+						"result": [{"id": "1", "name": "foo"}, {"id": "2", "name": "bar"}],
+						"success": true
+					}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/zones/123/widgets", "page=2&per_page=2"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"errors": [],
+						"messages": [],
+						"result": [{"id": "3", "name": "baz"}],
+						"success": true
+					}`),
+				),
+			)
+
+			items, err := cloudFlare.ResourceItems(paginated, "123")
+
+			Expect(err).To(BeNil())
+			Expect(items).To(Equal(ResourceItems{
+				{"id": "1", "name": "foo"},
+				{"id": "2", "name": "bar"},
+				{"id": "3", "name": "baz"},
+			}))
+		})
+	})
+
+	Describe("UpdateResource()", func() {
+		var (
+			server     *ghttp.Server
+			logbuf     *gbytes.Buffer
+			cloudFlare *CloudFlare
+		)
+
+		update := ResourceItemsForUpdate{
+			{
+				Key:      "qux",
+				Expected: ResourceItem{"name": "qux", "size": float64(4)},
+			},
+			{
+				Key:      "foo",
+				Current:  ResourceItem{"id": "1", "name": "foo", "size": float64(1), "modified_on": "yesterday"},
+				Expected: ResourceItem{"name": "foo", "size": float64(10)},
+			},
+			{
+				Key:     "baz",
+				Current: ResourceItem{"id": "3", "name": "baz", "size": float64(3)},
+			},
+		}
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			logbuf = gbytes.NewBuffer()
+			cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should create, update and delete items and log progress", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones/123/widgets"),
+					ghttp.VerifyJSON(`{"name": "qux", "size": 4}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PATCH", "/zones/123/widgets/1"),
+					ghttp.VerifyJSON(`{"name": "foo", "size": 10}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/zones/123/widgets/3"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			Expect(cloudFlare.UpdateResource(resource, "123", update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+
+			Expect(logbuf).To(gbytes.Say(`Creating widget "qux": {"name":"qux","size":4}`))
+			Expect(logbuf).To(gbytes.Say(`Changing widget "foo" from {"size":1} to {"size":10}`))
+			Expect(logbuf).To(gbytes.Say(`Deleting widget "baz"`))
+		})
+
+		It("should log progress without making changes when logOnly is true", func() {
+			Expect(cloudFlare.UpdateResource(resource, "123", update, true)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+
+			Expect(logbuf).To(gbytes.Say(`Would have created widget "qux"`))
+			Expect(logbuf).To(gbytes.Say(`Would have changed widget "foo"`))
+			Expect(logbuf).To(gbytes.Say(`Would have deleted widget "baz"`))
+		})
+
+		It("should keep the current fields of nested objects that aren't expected", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PATCH", "/zones/123/widgets/1"),
+					ghttp.VerifyJSON(`{"name": "foo", "size": {"width": 2, "height": 3}, "colour": {"name": "red"}}`),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			Expect(cloudFlare.UpdateResource(resource, "123", ResourceItemsForUpdate{
+				{
+					Key: "foo",
+					Current: ResourceItem{
+						"id":   "1",
+						"name": "foo",
+						"size": map[string]interface{}{"width": float64(1), "height": float64(3)},
+					},
+					Expected: ResourceItem{
+						"name":   "foo",
+						"size":   map[string]interface{}{"width": float64(2)},
+						"colour": map[string]interface{}{"name": "red"},
+					},
+				},
+			}, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+})
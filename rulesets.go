@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+)
+
+// RulesetPhases are downloaded when no phases are given. Each phase has an
+// entrypoint ruleset whose rules are evaluated in order.
+var RulesetPhases = []string{
+	"http_request_transform",
+	"http_request_late_transform",
+	"http_response_headers_transform",
+	"http_request_cache_settings",
+	"http_request_dynamic_redirect",
+	"http_request_origin",
+}
+
+// RulesetRulesResource describes the rules in a phase's entrypoint ruleset.
+// Rules are identified by their ref, which CloudFlare keep the same when a
+// rule is changed, or by their description if they don't have one. Rules
+// aren't managed individually, so it has no Path.
+var RulesetRulesResource = ResourceType{
+	Name:     "rule",
+	Key:      rulesetRuleKey,
+	Keys:     rulesetRuleKeys,
+	ReadOnly: []string{"id", "version", "last_updated"},
+}
+
+func rulesetRuleKey(item ResourceItem) string {
+	if ref, ok := item["ref"].(string); ok && ref != "" {
+		return ref
+	}
+
+	description, _ := item["description"].(string)
+	return description
+}
+
+// rulesetRuleKeys identifies rules by ref, except that expected rules
+// without one are identified by their description. CloudFlare gives every
+// rule a ref, so a current rule is identified by its description if an
+// expected rule without a ref has the same description and no expected
+// rule has its ref.
+func rulesetRuleKeys(current, expected ResourceItems) func(ResourceItem) string {
+	refs, descriptions := map[string]bool{}, map[string]bool{}
+	for _, item := range expected {
+		if ref, ok := item["ref"].(string); ok && ref != "" {
+			refs[ref] = true
+		} else {
+			descriptions[rulesetRuleKey(item)] = true
+		}
+	}
+
+	return func(item ResourceItem) string {
+		ref, _ := item["ref"].(string)
+		description, _ := item["description"].(string)
+		if ref != "" && !refs[ref] && descriptions[description] {
+			return description
+		}
+
+		return rulesetRuleKey(item)
+	}
+}
+
+// Rulesets are the ordered rules for each phase.
+type Rulesets map[string]ResourceItems
+
+// RulesetRuleForUpdate is a change to one rule in a phase. Position is the
+// rule's position in the expected rules, starting at 1, and Moved is true
+// if it has changed relative to the other rules.
+type RulesetRuleForUpdate struct {
+	ResourceItemForUpdate
+	Position int
+	Moved    bool
+}
+
+func (r RulesetRuleForUpdate) Action() string {
+	switch {
+	case r.Current == nil:
+		return "add"
+	case r.Expected == nil:
+		return "remove"
+	case !resourceValueMatches(map[string]interface{}(r.Current), map[string]interface{}(r.Expected)):
+		return "update"
+	}
+
+	return "move"
+}
+
+// RulesetForUpdate is a phase whose rules will be replaced by Rules, which
+// are the expected rules merged with the current rules they match.
+type RulesetForUpdate struct {
+	Phase   string
+	Current ResourceItems
+	Rules   ResourceItems
+	Changes []RulesetRuleForUpdate
+}
+
+type RulesetsForUpdate []RulesetForUpdate
+
+// CompareRulesetsForUpdate returns the phases whose rules differ. Phases
+// that aren't expected are left alone, so to remove all of the rules from
+// a phase it must be given an empty list.
+func CompareRulesetsForUpdate(current, expected Rulesets) (RulesetsForUpdate, error) {
+	update := RulesetsForUpdate{}
+
+	phases := []string{}
+	for phase := range expected {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+
+	for _, phase := range phases {
+		ruleset, err := compareRuleset(phase, current[phase], expected[phase])
+		if err != nil {
+			return nil, err
+		}
+		if len(ruleset.Changes) > 0 {
+			update = append(update, ruleset)
+		}
+	}
+
+	return update, nil
+}
+
+func compareRuleset(phase string, current, expected ResourceItems) (RulesetForUpdate, error) {
+	ruleset := RulesetForUpdate{Phase: phase, Current: current, Rules: ResourceItems{}}
+
+	keyFunc := rulesetRuleKeys(current, expected)
+	currentByKey, err := rulesetRulesByKey(current, keyFunc)
+	if err != nil {
+		return ruleset, err
+	}
+	expectedByKey, err := rulesetRulesByKey(expected, keyFunc)
+	if err != nil {
+		return ruleset, err
+	}
+
+	currentOrder, expectedOrder := []string{}, []string{}
+	for _, item := range current {
+		if key := keyFunc(item); expectedByKey[key] != nil {
+			currentOrder = append(currentOrder, key)
+		}
+	}
+	for _, item := range expected {
+		if key := keyFunc(item); currentByKey[key] != nil {
+			expectedOrder = append(expectedOrder, key)
+		}
+	}
+	unmoved := longestCommonSubsequence(currentOrder, expectedOrder)
+
+	for i, item := range expected {
+		key := keyFunc(item)
+		change := RulesetRuleForUpdate{
+			ResourceItemForUpdate: ResourceItemForUpdate{Key: key, Current: currentByKey[key], Expected: item},
+			Position:              i + 1,
+		}
+
+		rule := ResourceItem(copyResourceObject(item))
+		if change.Current != nil {
+			change.Moved = !unmoved[key]
+			rule = RulesetRulesResource.Strip(ResourceItems{change.Current})[0]
+			mergeResourceObject(rule, item)
+			rule["id"] = resourceID(change.Current)
+		}
+		ruleset.Rules = append(ruleset.Rules, rule)
+
+		if change.Current == nil || change.Moved || !resourceValueMatches(map[string]interface{}(change.Current), map[string]interface{}(item)) {
+			ruleset.Changes = append(ruleset.Changes, change)
+		}
+	}
+
+	for _, item := range current {
+		key := keyFunc(item)
+		if expectedByKey[key] == nil {
+			ruleset.Changes = append(ruleset.Changes, RulesetRuleForUpdate{
+				ResourceItemForUpdate: ResourceItemForUpdate{Key: key, Current: item},
+			})
+		}
+	}
+
+	return ruleset, nil
+}
+
+func rulesetRulesByKey(items ResourceItems, keyFunc func(ResourceItem) string) (map[string]ResourceItem, error) {
+	byKey := map[string]ResourceItem{}
+	for _, item := range items {
+		key := keyFunc(item)
+		if _, ok := byKey[key]; ok {
+			return nil, ResourceKeyDuplicated{Name: RulesetRulesResource.Name, Key: key}
+		}
+		byKey[key] = item
+	}
+
+	return byKey, nil
+}
+
+// longestCommonSubsequence returns the keys in the longest sequence that is
+// in the same order in a and b. Other keys are the fewest that have moved.
+func longestCommonSubsequence(a, b []string) map[string]bool {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	common := map[string]bool{}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return common
+}
+
+func rulesetPath(zone, phase string) string {
+	return fmt.Sprintf("/zones/%s/rulesets/phases/%s/entrypoint", zone, phase)
+}
+
+// PhaseRuleset returns the rules in a phase's entrypoint ruleset, which are
+// empty if the zone doesn't have one.
+func (c *CloudFlare) PhaseRuleset(zone, phase string) (ResourceItems, error) {
+	var ruleset struct {
+		Rules ResourceItems `json:"rules"`
+	}
+
+	req, err := c.Query.NewRequest("GET", rulesetPath(zone, phase))
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.MakeRequest(req)
+	if httpErr, ok := err.(CloudFlareHTTPError); ok && httpErr.StatusCode == http.StatusNotFound {
+		return ResourceItems{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(response.Result, &ruleset)
+	if ruleset.Rules == nil {
+		ruleset.Rules = ResourceItems{}
+	}
+
+	return ruleset.Rules, err
+}
+
+// Rulesets returns the rules for each of the phases that has any.
+func (c *CloudFlare) Rulesets(zone string, phases []string) (Rulesets, error) {
+	rulesets := Rulesets{}
+	for _, phase := range phases {
+		rules, err := c.PhaseRuleset(zone, phase)
+		if err != nil {
+			return nil, err
+		}
+		if len(rules) > 0 {
+			rulesets[phase] = rules
+		}
+	}
+
+	return rulesets, nil
+}
+
+// UpdatePhaseRuleset replaces the rules in a phase's entrypoint ruleset,
+// creating it if necessary.
+func (c *CloudFlare) UpdatePhaseRuleset(zone, phase string, rules ResourceItems) error {
+	_, err := c.sendResourceItem("PUT", rulesetPath(zone, phase), map[string]interface{}{"rules": rules})
+	return err
+}
+
+func (c *CloudFlare) UpdateRulesets(zone string, update RulesetsForUpdate, logOnly bool) error {
+	for _, ruleset := range update {
+		for _, change := range ruleset.Changes {
+			action := resourceAction(change.Action(), logOnly)
+
+			switch change.Action() {
+			case "add":
+				c.log.Printf("%s rule %q to %s at position %d: %s", action, change.Key,
+					ruleset.Phase, change.Position, jsonString(change.Expected))
+			case "update":
+				from, to := change.ChangedFields()
+				moved := ""
+				if change.Moved {
+					moved = fmt.Sprintf(" and moving it to position %d", change.Position)
+				}
+				c.log.Printf("%s rule %q in %s from %s to %s%s", action, change.Key,
+					ruleset.Phase, jsonString(from), jsonString(to), moved)
+			case "move":
+				c.log.Printf("%s rule %q in %s to position %d", action, change.Key,
+					ruleset.Phase, change.Position)
+			case "remove":
+				c.log.Printf("%s rule %q from %s", action, change.Key, ruleset.Phase)
+			}
+		}
+
+		if !logOnly {
+			err := c.UpdatePhaseRuleset(zone, ruleset.Phase, ruleset.Rules)
+			before := RulesetRulesResource.Strip(ruleset.Current)
+			after := RulesetRulesResource.Strip(ruleset.Rules)
+			if err = c.audit(zone, fmt.Sprintf("ruleset %s", ruleset.Phase), before, after, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func LoadRulesets(file string) (Rulesets, error) {
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var rulesets Rulesets
+	err = json.Unmarshal(bs, &rulesets)
+
+	return rulesets, err
+}
+
+func SaveRulesets(rulesets Rulesets, file string) error {
+	bs, err := json.MarshalIndent(rulesets, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(file, bs, 0644)
+	return err
+}
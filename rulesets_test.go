@@ -0,0 +1,310 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"log"
+	"net/http"
+)
+
+var _ = Describe("Rulesets", func() {
+	rule := func(ref, expression string) ResourceItem {
+		return ResourceItem{
+			"id":          "id-" + ref,
+			"ref":         ref,
+			"expression":  expression,
+			"action":      "redirect",
+			"description": "Rule " + ref,
+		}
+	}
+
+	expectedRule := func(ref, expression string) ResourceItem {
+		return ResourceItem{"ref": ref, "expression": expression}
+	}
+
+	Describe("CompareRulesetsForUpdate()", func() {
+		current := Rulesets{
+			"http_request_dynamic_redirect": ResourceItems{
+				rule("a", "true"),
+				rule("b", "true"),
+				rule("c", "true"),
+				rule("e", "true"),
+			},
+		}
+
+		It("should return nothing when rules match in the same order", func() {
+			update, err := CompareRulesetsForUpdate(current, Rulesets{
+				"http_request_dynamic_redirect": ResourceItems{
+					expectedRule("a", "true"),
+					expectedRule("b", "true"),
+					expectedRule("c", "true"),
+					expectedRule("e", "true"),
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update).To(Equal(RulesetsForUpdate{}))
+		})
+
+		It("should leave phases alone that aren't expected", func() {
+			update, err := CompareRulesetsForUpdate(current, Rulesets{})
+
+			Expect(err).To(BeNil())
+			Expect(update).To(Equal(RulesetsForUpdate{}))
+		})
+
+		It("should return added, removed, modified and reordered rules", func() {
+			update, err := CompareRulesetsForUpdate(current, Rulesets{
+				"http_request_dynamic_redirect": ResourceItems{
+					expectedRule("e", "true"),
+					expectedRule("d", "true"),
+					expectedRule("a", "false"),
+					expectedRule("c", "true"),
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update).To(HaveLen(1))
+			Expect(update[0].Phase).To(Equal("http_request_dynamic_redirect"))
+
+			changes := update[0].Changes
+			Expect(changes).To(HaveLen(4))
+
+			Expect(changes[0].Key).To(Equal("e"))
+			Expect(changes[0].Action()).To(Equal("move"))
+			Expect(changes[0].Position).To(Equal(1))
+
+			Expect(changes[1].Key).To(Equal("d"))
+			Expect(changes[1].Action()).To(Equal("add"))
+			Expect(changes[1].Position).To(Equal(2))
+
+			Expect(changes[2].Key).To(Equal("a"))
+			Expect(changes[2].Action()).To(Equal("update"))
+			Expect(changes[2].Moved).To(BeFalse())
+
+			Expect(changes[3].Key).To(Equal("b"))
+			Expect(changes[3].Action()).To(Equal("remove"))
+		})
+
+		It("should merge expected rules with the current rules they match", func() {
+			update, err := CompareRulesetsForUpdate(current, Rulesets{
+				"http_request_dynamic_redirect": ResourceItems{
+					expectedRule("a", "false"),
+					expectedRule("d", "true"),
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update[0].Rules).To(Equal(ResourceItems{
+				{"id": "id-a", "ref": "a", "expression": "false", "action": "redirect", "description": "Rule a"},
+				{"ref": "d", "expression": "true"},
+			}))
+		})
+
+		It("should keep nested fields of current rules that aren't expected", func() {
+			current := Rulesets{
+				"http_request_dynamic_redirect": ResourceItems{
+					{
+						"id":         "id-a",
+						"ref":        "a",
+						"expression": "true",
+						"action":     "redirect",
+						"action_parameters": map[string]interface{}{
+							"from_value": map[string]interface{}{
+								"status_code":           float64(301),
+								"preserve_query_string": true,
+							},
+						},
+					},
+				},
+			}
+
+			update, err := CompareRulesetsForUpdate(current, Rulesets{
+				"http_request_dynamic_redirect": ResourceItems{
+					{
+						"ref":        "a",
+						"expression": "false",
+						"action_parameters": map[string]interface{}{
+							"from_value": map[string]interface{}{"status_code": float64(302)},
+						},
+					},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update[0].Rules).To(Equal(ResourceItems{
+				{
+					"id":         "id-a",
+					"ref":        "a",
+					"expression": "false",
+					"action":     "redirect",
+					"action_parameters": map[string]interface{}{
+						"from_value": map[string]interface{}{
+							"status_code":           float64(302),
+							"preserve_query_string": true,
+						},
+					},
+				},
+			}))
+		})
+
+		It("should key rules without a ref by their description", func() {
+			update, err := CompareRulesetsForUpdate(Rulesets{}, Rulesets{
+				"http_request_transform": ResourceItems{
+					{"description": "Add header", "expression": "true"},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update[0].Changes[0].Key).To(Equal("Add header"))
+		})
+
+		It("should match rules without a ref to current rules with a server-assigned ref by description", func() {
+			current := Rulesets{
+				"http_request_transform": ResourceItems{
+					{"id": "3c1a8bd0", "ref": "3c1a8bd0", "description": "Add header", "expression": "true", "version": "1"},
+				},
+			}
+
+			update, err := CompareRulesetsForUpdate(current, Rulesets{
+				"http_request_transform": ResourceItems{
+					{"description": "Add header", "expression": "true"},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update).To(HaveLen(0))
+
+			update, err = CompareRulesetsForUpdate(current, Rulesets{
+				"http_request_transform": ResourceItems{
+					{"description": "Add header", "expression": "false"},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update[0].Changes).To(HaveLen(1))
+			Expect(update[0].Changes[0].Action()).To(Equal("update"))
+			Expect(update[0].Rules).To(Equal(ResourceItems{
+				{"id": "3c1a8bd0", "ref": "3c1a8bd0", "description": "Add header", "expression": "false"},
+			}))
+		})
+
+		It("should return an error when keys are duplicated", func() {
+			update, err := CompareRulesetsForUpdate(current, Rulesets{
+				"http_request_dynamic_redirect": ResourceItems{
+					expectedRule("a", "true"),
+					expectedRule("a", "false"),
+				},
+			})
+
+			Expect(update).To(BeNil())
+			Expect(err).To(MatchError(`More than one rule with the key "a"`))
+		})
+	})
+
+	Describe("CloudFlare", func() {
+		var (
+			server     *ghttp.Server
+			logbuf     *gbytes.Buffer
+			cloudFlare *CloudFlare
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			logbuf = gbytes.NewBuffer()
+			cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Describe("PhaseRuleset()", func() {
+			It("should return the entrypoint ruleset's rules", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/zones/123/rulesets/phases/http_request_transform/entrypoint"),
+						ghttp.RespondWith(http.StatusOK, `{
+							"errors": [],
+							"messages": [],
+							"result": {
+								"id": "2f2feab2026849078ba485f918791bdc",
+								"phase": "http_request_transform",
+								"rules": [{"id": "1", "ref": "a", "expression": "true"}]
+							},
+							"success": true
+						}`),
+					),
+				)
+
+				rules, err := cloudFlare.PhaseRuleset("123", "http_request_transform")
+
+				Expect(err).To(BeNil())
+				Expect(rules).To(Equal(ResourceItems{
+					{"id": "1", "ref": "a", "expression": "true"},
+				}))
+			})
+
+			It("should return no rules when the zone has no entrypoint ruleset", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/zones/123/rulesets/phases/http_request_transform/entrypoint"),
+						ghttp.RespondWith(http.StatusNotFound, `{"success": false}`),
+					),
+				)
+
+				rules, err := cloudFlare.PhaseRuleset("123", "http_request_transform")
+
+				Expect(err).To(BeNil())
+				Expect(rules).To(Equal(ResourceItems{}))
+			})
+		})
+
+		Describe("UpdateRulesets()", func() {
+			update := RulesetsForUpdate{
+				{
+					Phase: "http_request_dynamic_redirect",
+					Rules: ResourceItems{{"ref": "d", "expression": "true"}},
+					Changes: []RulesetRuleForUpdate{
+						{
+							ResourceItemForUpdate: ResourceItemForUpdate{Key: "d", Expected: ResourceItem{"ref": "d", "expression": "true"}},
+							Position:              1,
+						},
+						{
+							ResourceItemForUpdate: ResourceItemForUpdate{Key: "b", Current: ResourceItem{"ref": "b"}},
+						},
+					},
+				},
+			}
+
+			It("should replace the phase's rules and log changes", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("PUT", "/zones/123/rulesets/phases/http_request_dynamic_redirect/entrypoint"),
+						ghttp.VerifyJSON(`{"rules": [{"ref": "d", "expression": "true"}]}`),
+						ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+					),
+				)
+
+				Expect(cloudFlare.UpdateRulesets("123", update, false)).To(BeNil())
+				Expect(server.ReceivedRequests()).To(HaveLen(1))
+
+				Expect(logbuf).To(gbytes.Say(`Adding rule "d" to http_request_dynamic_redirect at position 1: {"expression":"true","ref":"d"}`))
+				Expect(logbuf).To(gbytes.Say(`Removing rule "b" from http_request_dynamic_redirect`))
+			})
+
+			It("should log changes without making them when logOnly is true", func() {
+				Expect(cloudFlare.UpdateRulesets("123", update, true)).To(BeNil())
+				Expect(server.ReceivedRequests()).To(HaveLen(0))
+
+				Expect(logbuf).To(gbytes.Say(`Would have added rule "d"`))
+				Expect(logbuf).To(gbytes.Say(`Would have removed rule "b"`))
+			})
+		})
+	})
+})
@@ -0,0 +1,139 @@
+package main
+
+// SettingSchema describes the values that CloudFlare accept for a zone
+// setting, or for one field of a setting whose value is an object.
+type SettingSchema struct {
+	// Type is one of "string", "number", "boolean", "object" or "array".
+	Type string
+	// Values, if not empty, enumerates every value that is allowed.
+	Values []interface{}
+	// Min and Max, if not nil, are the inclusive range of a number.
+	Min *float64
+	Max *float64
+	// Nullable allows null in addition to Type.
+	Nullable bool
+	// Fields describes each field of an object. Objects without Fields
+	// aren't checked any further.
+	Fields map[string]SettingSchema
+}
+
+func enumString(vals ...string) SettingSchema {
+	schema := SettingSchema{Type: "string"}
+	for _, val := range vals {
+		schema.Values = append(schema.Values, val)
+	}
+
+	return schema
+}
+
+func enumNumber(vals ...float64) SettingSchema {
+	schema := SettingSchema{Type: "number"}
+	for _, val := range vals {
+		schema.Values = append(schema.Values, val)
+	}
+
+	return schema
+}
+
+func rangeNumber(min, max float64) SettingSchema {
+	return SettingSchema{Type: "number", Min: &min, Max: &max}
+}
+
+var (
+	schemaOnOff   = enumString("on", "off")
+	schemaBoolean = SettingSchema{Type: "boolean"}
+)
+
+// SettingSchemas are the zone settings known to this tool. Settings that
+// CloudFlare introduce after this list was written will be reported as
+// unknown by validate, but can still be uploaded.
+var SettingSchemas = map[string]SettingSchema{
+	"0rtt":                     schemaOnOff,
+	"advanced_ddos":            schemaOnOff,
+	"always_online":            schemaOnOff,
+	"always_use_https":         schemaOnOff,
+	"automatic_https_rewrites": schemaOnOff,
+	"brotli":                   schemaOnOff,
+	"browser_cache_ttl": enumNumber(0, 30, 60, 120, 300, 1200, 1800, 3600, 7200,
+		10800, 14400, 18000, 28800, 43200, 57600, 72000, 86400, 172800, 259200,
+		345600, 432000, 691200, 1382400, 2073600, 2678400, 5356800, 16070400,
+		31536000),
+	"browser_check": schemaOnOff,
+	"cache_level":   enumString("aggressive", "basic", "simplified"),
+	"challenge_ttl": enumNumber(300, 900, 1800, 2700, 3600, 7200, 10800, 14400,
+		28800, 57600, 86400, 604800, 2592000, 31536000),
+	"ciphers":          {Type: "array"},
+	"cname_flattening": enumString("flatten_at_root", "flatten_all"),
+	"development_mode": schemaOnOff,
+	"early_hints":      schemaOnOff,
+	"edge_cache_ttl": enumNumber(30, 60, 300, 1200, 1800, 3600, 7200, 10800,
+		14400, 18000, 28800, 43200, 57600, 72000, 86400, 172800, 259200, 345600,
+		432000, 518400, 604800),
+	"email_obfuscation":  schemaOnOff,
+	"h2_prioritization":  enumString("on", "off", "custom"),
+	"hotlink_protection": schemaOnOff,
+	"http2":              schemaOnOff,
+	"http3":              schemaOnOff,
+	"image_resizing":     enumString("on", "off", "open"),
+	"ip_geolocation":     schemaOnOff,
+	"ipv6":               schemaOnOff,
+	"max_upload": enumNumber(100, 125, 150, 175, 200, 225, 250, 275, 300, 325,
+		350, 375, 400, 425, 450, 475, 500),
+	"min_tls_version": enumString("1.0", "1.1", "1.2", "1.3"),
+	"minify": {
+		Type: "object",
+		Fields: map[string]SettingSchema{
+			"css":  schemaOnOff,
+			"html": schemaOnOff,
+			"js":   schemaOnOff,
+		},
+	},
+	"mirage": schemaOnOff,
+	"mobile_redirect": {
+		Type: "object",
+		Fields: map[string]SettingSchema{
+			"status":           schemaOnOff,
+			"mobile_subdomain": {Type: "string", Nullable: true},
+			"strip_uri":        schemaBoolean,
+		},
+	},
+	"opportunistic_encryption":    schemaOnOff,
+	"opportunistic_onion":         schemaOnOff,
+	"orange_to_orange":            schemaOnOff,
+	"origin_error_page_pass_thru": schemaOnOff,
+	"origin_max_http_version":     enumString("1", "2"),
+	"polish":                      enumString("off", "lossless", "lossy"),
+	"prefetch_preload":            schemaOnOff,
+	"privacy_pass":                schemaOnOff,
+	"proxy_read_timeout":          rangeNumber(1, 6000),
+	"pseudo_ipv4":                 enumString("off", "add_header", "overwrite_header"),
+	"response_buffering":          schemaOnOff,
+	"rocket_loader":               schemaOnOff,
+	"security_header": {
+		Type: "object",
+		Fields: map[string]SettingSchema{
+			"strict_transport_security": {
+				Type: "object",
+				Fields: map[string]SettingSchema{
+					"enabled":            schemaBoolean,
+					"max_age":            rangeNumber(0, 31536000),
+					"include_subdomains": schemaBoolean,
+					"preload":            schemaBoolean,
+					"nosniff":            schemaBoolean,
+				},
+			},
+		},
+	},
+	"security_level":              enumString("off", "essentially_off", "low", "medium", "high", "under_attack"),
+	"server_side_exclude":         schemaOnOff,
+	"sort_query_string_for_cache": schemaOnOff,
+	"ssl":                         enumString("off", "flexible", "full", "strict"),
+	"tls_1_2_only":                schemaOnOff,
+	"tls_1_3":                     enumString("on", "off", "zrt"),
+	"tls_client_auth":             schemaOnOff,
+	"true_client_ip_header":       schemaOnOff,
+	"visitor_ip":                  schemaOnOff,
+	"waf":                         schemaOnOff,
+	"webp":                        schemaOnOff,
+	"websockets":                  schemaOnOff,
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is a problem with a value in a config file. Warnings are
+// for settings and fields that aren't known, which may have been added by
+// CloudFlare since SettingSchemas was written, so they don't make a config
+// invalid.
+type ValidationError struct {
+	File    string
+	Line    int
+	Column  int
+	Key     string
+	Message string
+	Warning bool
+}
+
+func (v ValidationError) Error() string {
+	if v.Warning {
+		return fmt.Sprintf("%s:%d:%d: warning: %s", v.File, v.Line, v.Column, v.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", v.File, v.Line, v.Column, v.Message)
+}
+
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	lines := []string{}
+	for _, err := range v {
+		lines = append(lines, err.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Invalid returns true if any of the errors aren't warnings.
+func (v ValidationErrors) Invalid() bool {
+	for _, err := range v {
+		if !err.Warning {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateConfigFile checks a config file against SettingSchemas without
+// contacting CloudFlare. Errors are ordered by their position in the file.
+func ValidateConfigFile(file string) ValidationErrors {
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ValidationErrors{{File: file, Message: err.Error()}}
+	}
+
+	var config ConfigItems
+	if err := json.Unmarshal(bs, &config); err != nil {
+		verr := ValidationError{File: file, Line: 1, Column: 1, Message: err.Error()}
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			verr.Line, verr.Column = lineColumn(bs, int(syntaxErr.Offset))
+		}
+		return ValidationErrors{verr}
+	}
+
+	offsets := jsonKeyOffsets(bs)
+	errs := ValidateConfigItems(config)
+	for i := range errs {
+		errs[i].File = file
+		errs[i].Line, errs[i].Column = lineColumn(bs, offsets[errs[i].Key])
+	}
+	sort.Sort(validationErrorsByPosition(errs))
+
+	return errs
+}
+
+// ValidateConfigItems checks config against SettingSchemas. The errors have
+// a Key, which is the dotted path to the invalid value, but no position.
+// Unknown settings and fields are warnings.
+func ValidateConfigItems(config ConfigItems) ValidationErrors {
+	errs := ValidationErrors{}
+	for _, key := range sortedKeys(config) {
+		val := config[key]
+		schema, ok := SettingSchemas[key]
+		if !ok {
+			errs = append(errs, ValidationError{
+				Key:     key,
+				Message: unknownMessage("setting", key, schemaKeys(SettingSchemas)),
+				Warning: true,
+			})
+			continue
+		}
+
+		errs = append(errs, validateValue(key, val, schema)...)
+	}
+
+	return errs
+}
+
+func validateValue(path string, val interface{}, schema SettingSchema) ValidationErrors {
+	invalid := func(format string, args ...interface{}) ValidationErrors {
+		return ValidationErrors{{
+			Key:     path,
+			Message: fmt.Sprintf("%q %s", path, fmt.Sprintf(format, args...)),
+		}}
+	}
+
+	if val == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return invalid("must be a %s, not null", schema.Type)
+	}
+
+	if actual := jsonType(val); actual != schema.Type {
+		return invalid("must be a %s, not %s %s", schema.Type, actual, jsonString(val))
+	}
+
+	if len(schema.Values) > 0 && !containsValue(schema.Values, val) {
+		allowed := []string{}
+		for _, v := range schema.Values {
+			allowed = append(allowed, jsonString(v))
+		}
+
+		message := fmt.Sprintf("has invalid value %s, must be one of: %s",
+			jsonString(val), strings.Join(allowed, ", "))
+		if str, ok := val.(string); ok {
+			if suggestion := closest(str, stringValues(schema.Values)); suggestion != "" {
+				message = fmt.Sprintf("%s (did you mean %q?)", message, suggestion)
+			}
+		}
+		return invalid("%s", message)
+	}
+
+	if num, ok := val.(float64); ok && schema.Min != nil && schema.Max != nil {
+		if num < *schema.Min || num > *schema.Max {
+			return invalid("has value %v, must be between %v and %v", num, *schema.Min, *schema.Max)
+		}
+	}
+
+	obj, ok := val.(map[string]interface{})
+	if !ok || schema.Fields == nil {
+		return nil
+	}
+
+	errs := ValidationErrors{}
+	for _, field := range sortedKeys(obj) {
+		fieldVal := obj[field]
+		fieldPath := fmt.Sprintf("%s.%s", path, field)
+		fieldSchema, ok := schema.Fields[field]
+		if !ok {
+			errs = append(errs, ValidationError{
+				Key:     fieldPath,
+				Message: unknownMessage(fmt.Sprintf("field of %q", path), field, schemaKeys(schema.Fields)),
+				Warning: true,
+			})
+			continue
+		}
+
+		errs = append(errs, validateValue(fieldPath, fieldVal, fieldSchema)...)
+	}
+
+	return errs
+}
+
+func unknownMessage(kind, name string, known []string) string {
+	message := fmt.Sprintf("unknown %s %q", kind, name)
+	if suggestion := closest(name, known); suggestion != "" {
+		message = fmt.Sprintf("%s (did you mean %q?)", message, suggestion)
+	}
+
+	return message
+}
+
+func jsonType(val interface{}) string {
+	switch val.(type) {
+	case string:
+		return "string"
+	case float64, int:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+
+	return fmt.Sprintf("%T", val)
+}
+
+func jsonString(val interface{}) string {
+	bs, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%#v", val)
+	}
+
+	return string(bs)
+}
+
+func containsValue(vals []interface{}, val interface{}) bool {
+	if num, ok := val.(int); ok {
+		val = float64(num)
+	}
+
+	for _, v := range vals {
+		if reflect.DeepEqual(v, val) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stringValues(vals []interface{}) []string {
+	strs := []string{}
+	for _, val := range vals {
+		if str, ok := val.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+
+	return strs
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := []string{}
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func schemaKeys(schemas map[string]SettingSchema) []string {
+	keys := []string{}
+	for key := range schemas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// closest returns the candidate with the smallest edit distance from name,
+// or an empty string if none are close enough to be a likely typo.
+func closest(name string, candidates []string) string {
+	best := ""
+	bestDistance := len(name)/3 + 2
+
+	for _, candidate := range candidates {
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j] + 1
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+type jsonFrame struct {
+	object    bool
+	expectKey bool
+	key       string
+	index     int
+}
+
+// jsonKeyOffsets returns the byte offset of every object key in a JSON
+// document, indexed by its dotted path such as "minify.css". Array elements
+// are included in the path by their index.
+func jsonKeyOffsets(bs []byte) map[string]int {
+	offsets := map[string]int{}
+	stack := []*jsonFrame{}
+
+	for i := 0; i < len(bs); i++ {
+		switch bs[i] {
+		case '"':
+			start := i
+			for i++; i < len(bs) && bs[i] != '"'; i++ {
+				if bs[i] == '\\' {
+					i++
+				}
+			}
+
+			if len(stack) == 0 {
+				continue
+			}
+			top := stack[len(stack)-1]
+			if !top.object || !top.expectKey {
+				continue
+			}
+
+			var key string
+			if end := i + 1; end <= len(bs) && json.Unmarshal(bs[start:end], &key) == nil {
+				top.key = key
+				top.expectKey = false
+
+				path := []string{}
+				for _, frame := range stack {
+					if frame.object {
+						path = append(path, frame.key)
+					} else {
+						path = append(path, strconv.Itoa(frame.index))
+					}
+				}
+				offsets[strings.Join(path, ".")] = start
+			}
+		case '{':
+			stack = append(stack, &jsonFrame{object: true, expectKey: true})
+		case '[':
+			stack = append(stack, &jsonFrame{})
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ',':
+			if len(stack) > 0 {
+				top := stack[len(stack)-1]
+				top.expectKey = top.object
+				top.index++
+			}
+		}
+	}
+
+	return offsets
+}
+
+// lineColumn converts a byte offset to a one-indexed line and column.
+func lineColumn(bs []byte, offset int) (int, int) {
+	if offset > len(bs) {
+		offset = len(bs)
+	}
+
+	line, column := 1, 1
+	for _, b := range bs[:offset] {
+		if b == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+
+	return line, column
+}
+
+type validationErrorsByPosition ValidationErrors
+
+func (v validationErrorsByPosition) Len() int      { return len(v) }
+func (v validationErrorsByPosition) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v validationErrorsByPosition) Less(i, j int) bool {
+	if v[i].Line != v[j].Line {
+		return v[i].Line < v[j].Line
+	}
+	if v[i].Column != v[j].Column {
+		return v[i].Column < v[j].Column
+	}
+
+	return v[i].Key < v[j].Key
+}
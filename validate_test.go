@@ -0,0 +1,161 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Validate", func() {
+	Describe("ValidateConfigItems()", func() {
+		It("should return nothing for valid config", func() {
+			Expect(ValidateConfigItems(ConfigItems{
+				"always_online":     "on",
+				"browser_cache_ttl": float64(14400),
+				"min_tls_version":   "1.2",
+				"mobile_redirect": map[string]interface{}{
+					"mobile_subdomain": nil,
+					"status":           "off",
+					"strip_uri":        false,
+				},
+			})).To(BeEmpty())
+		})
+
+		It("should suggest a known setting for a typo", func() {
+			Expect(ValidateConfigItems(ConfigItems{
+				"alway_online": "on",
+			})).To(Equal(ValidationErrors{{
+				Key:     "alway_online",
+				Message: `unknown setting "alway_online" (did you mean "always_online"?)`,
+				Warning: true,
+			}}))
+		})
+
+		It("should report values of the wrong type", func() {
+			Expect(ValidateConfigItems(ConfigItems{
+				"ipv6": true,
+			})).To(Equal(ValidationErrors{{
+				Key:     "ipv6",
+				Message: `"ipv6" must be a string, not boolean true`,
+			}}))
+		})
+
+		It("should report enumerated values that aren't allowed", func() {
+			Expect(ValidateConfigItems(ConfigItems{
+				"ssl":               "flexibel",
+				"browser_cache_ttl": float64(1000),
+			})).To(Equal(ValidationErrors{
+				{
+					Key:     "browser_cache_ttl",
+					Message: `"browser_cache_ttl" has invalid value 1000, must be one of: 0, 30, 60, 120, 300, 1200, 1800, 3600, 7200, 10800, 14400, 18000, 28800, 43200, 57600, 72000, 86400, 172800, 259200, 345600, 432000, 691200, 1382400, 2073600, 2678400, 5356800, 16070400, 31536000`,
+				},
+				{
+					Key:     "ssl",
+					Message: `"ssl" has invalid value "flexibel", must be one of: "off", "flexible", "full", "strict" (did you mean "flexible"?)`,
+				},
+			}))
+		})
+
+		It("should report numbers out of range", func() {
+			Expect(ValidateConfigItems(ConfigItems{
+				"proxy_read_timeout": float64(9000),
+			})).To(Equal(ValidationErrors{{
+				Key:     "proxy_read_timeout",
+				Message: `"proxy_read_timeout" has value 9000, must be between 1 and 6000`,
+			}}))
+		})
+
+		It("should check the fields of objects", func() {
+			Expect(ValidateConfigItems(ConfigItems{
+				"minify": map[string]interface{}{
+					"css":   "on",
+					"htmll": "on",
+					"js":    "yes",
+				},
+			})).To(Equal(ValidationErrors{
+				{
+					Key:     "minify.htmll",
+					Message: `unknown field of "minify" "htmll" (did you mean "html"?)`,
+					Warning: true,
+				},
+				{
+					Key:     "minify.js",
+					Message: `"minify.js" has invalid value "yes", must be one of: "on", "off"`,
+				},
+			}))
+		})
+	})
+
+	Describe("ValidateConfigFile()", func() {
+		var (
+			tempDir  string
+			tempFile string
+		)
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = ioutil.TempDir("", "cloudflare-configure")
+			Expect(err).To(BeNil())
+
+			tempFile = filepath.Join(tempDir, "cloudflare-configure.json")
+		})
+
+		AfterEach(func() {
+			err := os.RemoveAll(tempDir)
+			Expect(err).To(BeNil())
+		})
+
+		It("should report the line and column of each error in file order", func() {
+			err := ioutil.WriteFile(tempFile, []byte(`{
+    "ssl": "flexibel",
+    "always_online": "on",
+    "minify": {
+        "css": "on",
+        "html": "on",
+        "jss": "on"
+    },
+    "alway_online": "on"
+}`), 0644)
+			Expect(err).To(BeNil())
+
+			errs := ValidateConfigFile(tempFile)
+			Expect(errs).To(HaveLen(3))
+			Expect(errs[0].Line).To(Equal(2))
+			Expect(errs[0].Column).To(Equal(5))
+			Expect(errs[0].Key).To(Equal("ssl"))
+			Expect(errs[1].Line).To(Equal(7))
+			Expect(errs[1].Column).To(Equal(9))
+			Expect(errs[1].Key).To(Equal("minify.jss"))
+			Expect(errs[2].Error()).To(Equal(tempFile +
+				`:9:5: warning: unknown setting "alway_online" (did you mean "always_online"?)`))
+			Expect(errs.Invalid()).To(BeTrue())
+		})
+
+		It("should only warn about settings that aren't known", func() {
+			err := ioutil.WriteFile(tempFile, []byte(`{
+    "ssl": "full",
+    "new_feature": "on"
+}`), 0644)
+			Expect(err).To(BeNil())
+
+			errs := ValidateConfigFile(tempFile)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Error()).To(Equal(tempFile + `:3:5: warning: unknown setting "new_feature"`))
+			Expect(errs.Invalid()).To(BeFalse())
+		})
+
+		It("should report the position of JSON syntax errors", func() {
+			err := ioutil.WriteFile(tempFile, []byte("{\n    \"ssl\": \"full\"\n    \"waf\": \"on\"\n}"), 0644)
+			Expect(err).To(BeNil())
+
+			errs := ValidateConfigFile(tempFile)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Line).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+)
+
+// WorkerRoutesResource manages the routes that map a zone's URL patterns to
+// Workers scripts. Routes are identified by their pattern.
+var WorkerRoutesResource = ResourceType{
+	Name:         "worker route",
+	Path:         "/zones/%s/workers/routes",
+	Key:          workerRouteKey,
+	ReadOnly:     []string{"id"},
+	UpdateMethod: "PUT",
+}
+
+func workerRouteKey(item ResourceItem) string {
+	pattern, _ := item["pattern"].(string)
+	return pattern
+}
+
+// WorkersConfig is the Workers scripts to deploy, by name, and a zone's
+// routes. Script files are relative to the config file.
+type WorkersConfig struct {
+	Scripts map[string]string `json:"scripts"`
+	Routes  ResourceItems     `json:"routes"`
+}
+
+func workerScriptPath(account, name string) string {
+	return fmt.Sprintf("/accounts/%s/workers/scripts/%s", account, name)
+}
+
+// WorkerScriptHash is used to compare scripts without logging their
+// content.
+func WorkerScriptHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// WorkerScript returns the content of a script, which is nil if the script
+// doesn't exist. Scripts are returned as they were uploaded, rather than
+// in the usual response envelope.
+func (c *CloudFlare) WorkerScript(account, name string) ([]byte, error) {
+	req, err := c.Query.NewRequest("GET", workerScriptPath(account, name))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.requestBody(req)
+	if httpErr, ok := err.(CloudFlareHTTPError); ok && httpErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	return body, err
+}
+
+func (c *CloudFlare) UploadWorkerScript(account, name string, content []byte) error {
+	req, err := c.Query.NewRequestBody("PUT", workerScriptPath(account, name), bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/javascript")
+
+	_, err = c.MakeRequest(req)
+
+	return err
+}
+
+// WorkerScriptForUpdate is a script whose local content differs from the
+// deployed script. CurrentHash is empty for scripts that don't exist yet.
+type WorkerScriptForUpdate struct {
+	Name         string
+	Content      []byte
+	CurrentHash  string
+	ExpectedHash string
+}
+
+// CompareWorkerScriptsForUpdate reads each script file and compares it with
+// the deployed script by hash, so that unchanged scripts aren't uploaded
+// again. Scripts that aren't in the config are left alone, because they
+// may be used by other zones.
+func (c *CloudFlare) CompareWorkerScriptsForUpdate(account string, scripts map[string]string) ([]WorkerScriptForUpdate, error) {
+	names := []string{}
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	update := []WorkerScriptForUpdate{}
+	for _, name := range names {
+		content, err := ioutil.ReadFile(scripts[name])
+		if err != nil {
+			return nil, err
+		}
+
+		current, err := c.WorkerScript(account, name)
+		if err != nil {
+			return nil, err
+		}
+
+		change := WorkerScriptForUpdate{
+			Name:         name,
+			Content:      content,
+			ExpectedHash: WorkerScriptHash(content),
+		}
+		if current != nil {
+			change.CurrentHash = WorkerScriptHash(current)
+		}
+
+		if change.CurrentHash != change.ExpectedHash {
+			update = append(update, change)
+		}
+	}
+
+	return update, nil
+}
+
+func (c *CloudFlare) UpdateWorkerScripts(account string, update []WorkerScriptForUpdate, logOnly bool) error {
+	for _, change := range update {
+		if change.CurrentHash == "" {
+			c.log.Printf("%s worker script %q with sha256 %s", resourceAction("create", logOnly),
+				change.Name, change.ExpectedHash)
+		} else {
+			c.log.Printf("%s worker script %q from sha256 %s to %s", resourceAction("update", logOnly),
+				change.Name, change.CurrentHash, change.ExpectedHash)
+		}
+
+		if logOnly {
+			continue
+		}
+
+		var before interface{}
+		if change.CurrentHash != "" {
+			before = change.CurrentHash
+		}
+
+		err := c.UploadWorkerScript(account, change.Name, change.Content)
+		if err = c.audit(account, fmt.Sprintf("worker script %s", change.Name), before, change.ExpectedHash, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadWorkersConfig reads a config file, making the paths of script files
+// relative to the current directory.
+func LoadWorkersConfig(file string) (WorkersConfig, error) {
+	var config WorkersConfig
+
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+
+	if err = json.Unmarshal(bs, &config); err != nil {
+		return config, err
+	}
+
+	for name, script := range config.Scripts {
+		if !filepath.IsAbs(script) {
+			config.Scripts[name] = filepath.Join(filepath.Dir(file), script)
+		}
+	}
+
+	return config, nil
+}
+
+func SaveWorkersConfig(config WorkersConfig, file string) error {
+	bs, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(file, bs, 0644)
+	return err
+}
@@ -0,0 +1,133 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Workers", func() {
+	var (
+		server     *ghttp.Server
+		logbuf     *gbytes.Buffer
+		cloudFlare *CloudFlare
+		dir        string
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		logbuf = gbytes.NewBuffer()
+		cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(logbuf, "", 0))
+
+		var err error
+		dir, err = ioutil.TempDir("", "cloudflare-configure")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	writeFile := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(file, []byte(content), 0644)).To(BeNil())
+		return file
+	}
+
+	Describe("LoadWorkersConfig()", func() {
+		It("should make script paths relative to the config file", func() {
+			file := writeFile("workers.json", `{
+				"scripts": {"redirects": "redirects.js", "absolute": "/srv/absolute.js"},
+				"routes": [{"pattern": "example.com/*", "script": "redirects"}]
+			}`)
+
+			config, err := LoadWorkersConfig(file)
+
+			Expect(err).To(BeNil())
+			Expect(config.Scripts).To(Equal(map[string]string{
+				"redirects": filepath.Join(dir, "redirects.js"),
+				"absolute":  "/srv/absolute.js",
+			}))
+			Expect(config.Routes).To(Equal(ResourceItems{
+				{"pattern": "example.com/*", "script": "redirects"},
+			}))
+		})
+	})
+
+	Describe("CompareWorkerScriptsForUpdate()", func() {
+		It("should only return scripts whose content has changed", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/workers/scripts/changed"),
+					ghttp.RespondWith(http.StatusOK, "old();"),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/workers/scripts/new"),
+					ghttp.RespondWith(http.StatusNotFound, `{"success": false}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/accounts/abc/workers/scripts/unchanged"),
+					ghttp.RespondWith(http.StatusOK, "same();"),
+				),
+			)
+
+			update, err := cloudFlare.CompareWorkerScriptsForUpdate("abc", map[string]string{
+				"changed":   writeFile("changed.js", "new();"),
+				"new":       writeFile("new.js", "new();"),
+				"unchanged": writeFile("unchanged.js", "same();"),
+			})
+
+			Expect(err).To(BeNil())
+			Expect(update).To(Equal([]WorkerScriptForUpdate{
+				{
+					Name:         "changed",
+					Content:      []byte("new();"),
+					CurrentHash:  WorkerScriptHash([]byte("old();")),
+					ExpectedHash: WorkerScriptHash([]byte("new();")),
+				},
+				{
+					Name:         "new",
+					Content:      []byte("new();"),
+					ExpectedHash: WorkerScriptHash([]byte("new();")),
+				},
+			}))
+		})
+	})
+
+	Describe("UpdateWorkerScripts()", func() {
+		update := []WorkerScriptForUpdate{
+			{Name: "redirects", Content: []byte("new();"), CurrentHash: "aaa", ExpectedHash: "bbb"},
+		}
+
+		It("should upload scripts as JavaScript", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/accounts/abc/workers/scripts/redirects"),
+					ghttp.VerifyContentType("application/javascript"),
+					ghttp.VerifyBody([]byte("new();")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, CloudFlareResponse{Success: true}),
+				),
+			)
+
+			Expect(cloudFlare.UpdateWorkerScripts("abc", update, false)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+			Expect(logbuf).To(gbytes.Say(`Changing worker script "redirects" from sha256 aaa to bbb`))
+		})
+
+		It("should log without uploading when logOnly is true", func() {
+			Expect(cloudFlare.UpdateWorkerScripts("abc", update, true)).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(0))
+			Expect(logbuf).To(gbytes.Say(`Would have changed worker script "redirects"`))
+		})
+	})
+})
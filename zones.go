@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type CloudFlareZoneCreate struct {
+	Name      string                       `json:"name"`
+	Account   *CloudFlareZoneCreateAccount `json:"account,omitempty"`
+	JumpStart bool                         `json:"jump_start"`
+	Type      string                       `json:"type,omitempty"`
+}
+
+type CloudFlareZoneCreateAccount struct {
+	ID string `json:"id"`
+}
+
+// sendZone makes a request that returns a zone.
+func (c *CloudFlare) sendZone(method, path string, body interface{}) (CloudFlareZoneItem, error) {
+	var zone CloudFlareZoneItem
+
+	result, err := c.sendResourceItem(method, path, body)
+	if err != nil {
+		return zone, err
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return zone, err
+	}
+	err = json.Unmarshal(bs, &zone)
+
+	return zone, err
+}
+
+// CreateZone adds a zone to an account, or to the user's account if the
+// account isn't given. With JumpStart, CloudFlare scans for existing DNS
+// records and import them.
+func (c *CloudFlare) CreateZone(create CloudFlareZoneCreate) (CloudFlareZoneItem, error) {
+	return c.sendZone("POST", "/zones", create)
+}
+
+// PauseZone stops CloudFlare from proxying traffic for a zone, while still
+// serving its DNS, or starts proxying it again.
+func (c *CloudFlare) PauseZone(zoneID string, paused bool) (CloudFlareZoneItem, error) {
+	return c.sendZone("PATCH", fmt.Sprintf("/zones/%s", zoneID), map[string]interface{}{"paused": paused})
+}
+
+func (c *CloudFlare) DeleteZone(zoneID string) error {
+	_, err := c.sendResourceItem("DELETE", fmt.Sprintf("/zones/%s", zoneID), nil)
+	return err
+}
+
+// CheckZoneActivation asks CloudFlare to check whether a pending zone's
+// name servers have been changed to theirs, instead of waiting for them to
+// check periodically.
+func (c *CloudFlare) CheckZoneActivation(zoneID string) error {
+	_, err := c.sendResourceItem("PUT", fmt.Sprintf("/zones/%s/activation_check", zoneID), nil)
+	return err
+}
+
+// WriteZoneActivation writes a zone's status and, until it's active, the
+// name servers that its registrar must be changed to use.
+func WriteZoneActivation(w io.Writer, zone CloudFlareZoneItem) error {
+	status := zone.Status
+	if zone.Paused {
+		status = fmt.Sprintf("%s (paused)", status)
+	}
+
+	if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", zone.ID, zone.Name, status); err != nil {
+		return err
+	}
+	if zone.Status == "active" {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "Change name servers from: %s\nChange name servers to:   %s\n",
+		strings.Join(zone.OriginalNameServers, ", "), strings.Join(zone.NameServers, ", "))
+
+	return err
+}
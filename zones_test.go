@@ -0,0 +1,157 @@
+package main_test
+
+import (
+	. "github.com/alphagov/cloudflare-configure"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	"bytes"
+	"log"
+	"net/http"
+)
+
+var _ = Describe("Zones", func() {
+	var (
+		server     *ghttp.Server
+		cloudFlare *CloudFlare
+	)
+
+	respondWithZone := func(result string) http.HandlerFunc {
+		return ghttp.RespondWith(http.StatusOK, `{"errors": [], "messages": [], "success": true, "result": `+result+`}`)
+	}
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		cloudFlare = NewCloudFlare(&CloudFlareQuery{RootURL: server.URL()}, log.New(gbytes.NewBuffer(), "", 0))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("CreateZone()", func() {
+		It("should add the zone to the account and return it", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/zones"),
+					ghttp.VerifyJSON(`{
+						"name": "example.com",
+						"account": {"id": "abc"},
+						"jump_start": true,
+						"type": "full"
+					}`),
+					respondWithZone(`{
+						"id": "123",
+						"name": "example.com",
+						"status": "pending",
+						"name_servers": ["ada.ns.cloudflare.com", "bob.ns.cloudflare.com"]
+					}`),
+				),
+			)
+
+			zone, err := cloudFlare.CreateZone(CloudFlareZoneCreate{
+				Name:      "example.com",
+				Account:   &CloudFlareZoneCreateAccount{ID: "abc"},
+				JumpStart: true,
+				Type:      "full",
+			})
+
+			Expect(err).To(BeNil())
+			Expect(zone).To(Equal(CloudFlareZoneItem{
+				ID:          "123",
+				Name:        "example.com",
+				Status:      "pending",
+				NameServers: []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"},
+			}))
+		})
+	})
+
+	Describe("PauseZone()", func() {
+		It("should set whether the zone is paused", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PATCH", "/zones/123"),
+					ghttp.VerifyJSON(`{"paused": true}`),
+					respondWithZone(`{"id": "123", "name": "example.com", "status": "active", "paused": true}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PATCH", "/zones/123"),
+					ghttp.VerifyJSON(`{"paused": false}`),
+					respondWithZone(`{"id": "123", "name": "example.com", "status": "active", "paused": false}`),
+				),
+			)
+
+			zone, err := cloudFlare.PauseZone("123", true)
+			Expect(err).To(BeNil())
+			Expect(zone.Paused).To(BeTrue())
+
+			zone, err = cloudFlare.PauseZone("123", false)
+			Expect(err).To(BeNil())
+			Expect(zone.Paused).To(BeFalse())
+		})
+	})
+
+	Describe("DeleteZone()", func() {
+		It("should delete the zone", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/zones/123"),
+					respondWithZone(`{"id": "123"}`),
+				),
+			)
+
+			Expect(cloudFlare.DeleteZone("123")).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+
+	Describe("CheckZoneActivation()", func() {
+		It("should request an activation check", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/zones/123/activation_check"),
+					respondWithZone(`{"id": "123"}`),
+				),
+			)
+
+			Expect(cloudFlare.CheckZoneActivation("123")).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+
+	Describe("WriteZoneActivation()", func() {
+		It("should show the name servers to change to for a pending zone", func() {
+			buf := &bytes.Buffer{}
+			err := WriteZoneActivation(buf, CloudFlareZoneItem{
+				ID:                  "123",
+				Name:                "example.com",
+				Status:              "pending",
+				NameServers:         []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"},
+				OriginalNameServers: []string{"ns1.example.net", "ns2.example.net"},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(Equal(`123	example.com	pending
+Change name servers from: ns1.example.net, ns2.example.net
+Change name servers to:   ada.ns.cloudflare.com, bob.ns.cloudflare.com
+`))
+		})
+
+		It("should only show the status of an active zone", func() {
+			buf := &bytes.Buffer{}
+			err := WriteZoneActivation(buf, CloudFlareZoneItem{
+				ID:          "123",
+				Name:        "example.com",
+				Status:      "active",
+				Paused:      true,
+				NameServers: []string{"ada.ns.cloudflare.com"},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(Equal("123\texample.com\tactive (paused)\n"))
+		})
+	})
+})